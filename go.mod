@@ -1,28 +1,49 @@
 module github.com/celogeek/go-comic-converter/v2
 
-go 1.23
+go 1.23.0
 
 require (
 	github.com/beevik/etree v1.4.1
+	github.com/bodgit/sevenzip v1.6.0
 	github.com/disintegration/gift v1.2.1
 	github.com/fogleman/gg v1.3.0
+	github.com/gen2brain/avif v0.4.4
+	github.com/gen2brain/go-fitz v1.24.15
+	github.com/gen2brain/heic v0.4.5
+	github.com/gen2brain/jpegxl v0.4.5
 	github.com/gofrs/uuid v4.4.0+incompatible
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/klauspost/compress v1.17.9
 	github.com/nwaples/rardecode/v2 v2.0.1
 	github.com/raff/pdfreader v0.0.0-20220308062436-033e8ac577f0
 	github.com/schollz/progressbar/v3 v3.17.1
 	github.com/tcnksm/go-latest v0.0.0-20170313132115-e3007ae9052e
+	golang.org/x/crypto v0.31.0
 	golang.org/x/image v0.23.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/bodgit/plumbing v1.3.0 // indirect
+	github.com/bodgit/windows v1.0.1 // indirect
+	github.com/ebitengine/purego v0.8.4 // indirect
 	github.com/google/go-github v17.0.0+incompatible // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/hashicorp/go-version v1.7.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/jupiterrider/ffi v0.5.0 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
+	github.com/ulikunitz/xz v0.5.12 // indirect
+	go4.org v0.0.0-20200411211856-f5505b9728dd // indirect
 	golang.org/x/net v0.33.0 // indirect
-	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/term v0.27.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
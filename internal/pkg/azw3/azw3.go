@@ -0,0 +1,490 @@
+/*
+Package azw3 Write Kindle books in the KF8 format (AZW3).
+
+The book is a Palm database: the record 0 describes the book (MOBI header and EXTH metadata),
+the following records contain the text, the indexes, and the images.
+
+Each page is stored as a skeleton with a single fragment containing the body of the page.
+The images and the stylesheet are referenced with the kindle:embed and kindle:flow urls.
+*/
+package azw3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	textRecordSize = 4096
+	nullIndex      = 0xffffffff
+)
+
+// Meta metadata of the book, using the names of the OPF metadata
+type Meta struct {
+	Name  string
+	Value string
+}
+
+// Chapter entry of the table of content, pointing to a page
+type Chapter struct {
+	Title string
+	Page  int
+}
+
+// Resource image of the book
+type Resource struct {
+	Data []byte
+	Mime string
+}
+
+type Book struct {
+	UID         string
+	Title       string
+	Meta        []Meta
+	RightToLeft bool
+	CSS         string
+	// Pages xhtml of the pages, in the reading order
+	Pages     []string
+	Resources []Resource
+	// CoverId index of the cover in the resources, -1 without cover
+	CoverId  int
+	Chapters []Chapter
+}
+
+// EmbedURL url of a resource from a page
+func EmbedURL(id int, mime string) string {
+	return "kindle:embed:" + base32(id+1, 4) + "?mime=" + mime
+}
+
+// FlowURL url of the stylesheet from a page
+func FlowURL() string {
+	return "kindle:flow:0001?mime=text/css"
+}
+
+// base32 with the digits 0-9A-V, used for the kindle urls and the aid attributes
+func base32(n, width int) string {
+	const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUV"
+	var b []byte
+	for ; n > 0 || len(b) < width; n /= 32 {
+		b = append([]byte{digits[n%32]}, b...)
+	}
+	return string(b)
+}
+
+// WriteFile create the book
+func (b Book) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = b.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write the palm database of the book
+func (b Book) Write(w io.Writer) error {
+	if len(b.Pages) == 0 {
+		return errors.New("azw3: no page")
+	}
+
+	text, skel, frag, pagePos, err := b.text()
+	if err != nil {
+		return err
+	}
+	html := len(text)
+	text = append(text, b.CSS...)
+
+	textRecords := b.textRecords(text)
+
+	// record 0 is written last as it references the other records
+	records := [][]byte{nil}
+	records = append(records, textRecords...)
+	firstNonText := len(records)
+
+	fragIndex := len(records)
+	records = append(records, frag.records()...)
+	skelIndex := len(records)
+	records = append(records, skel.records()...)
+	ncxIndex := uint32(nullIndex)
+	if ncx, ok := b.ncx(html, pagePos); ok {
+		ncxIndex = uint32(len(records))
+		records = append(records, ncx.records()...)
+	}
+
+	firstResource := uint32(nullIndex)
+	if len(b.Resources) > 0 {
+		firstResource = uint32(len(records))
+	}
+	for _, r := range b.Resources {
+		records = append(records, r.Data)
+	}
+
+	fdst := []byte("FDST")
+	for _, v := range []uint32{12, 2, 0, uint32(html), uint32(html), uint32(len(text))} {
+		fdst = binary.BigEndian.AppendUint32(fdst, v)
+	}
+	fdstRecord := len(records)
+	records = append(records, fdst)
+
+	flisRecord := len(records)
+	records = append(records, flis())
+	fcisRecord := len(records)
+	records = append(records, fcis(len(text)))
+	records = append(records, []byte{0xe9, 0x8e, 0x0d, 0x0a})
+
+	records[0] = b.record0(record0Options{
+		textLength:    len(text),
+		textRecords:   len(textRecords),
+		firstNonText:  uint32(firstNonText),
+		firstResource: firstResource,
+		fdst:          uint32(fdstRecord),
+		fcis:          uint32(fcisRecord),
+		flis:          uint32(flisRecord),
+		ncx:           ncxIndex,
+		frag:          uint32(fragIndex),
+		skel:          uint32(skelIndex),
+	})
+
+	return b.writeDatabase(w, records)
+}
+
+// text of the pages, each page is split into a skeleton and a fragment with the content of the body.
+//
+// it returns the position of each page in the text.
+func (b Book) text() ([]byte, index, index, []int, error) {
+	skel := index{tags: []tagMeta{{1, 1, 3, 0}, {6, 2, 12, 0}, endTagTable}}
+	frag := index{tags: []tagMeta{{2, 1, 1, 0}, {3, 1, 2, 0}, {4, 1, 4, 0}, {6, 2, 8, 0}, endTagTable}}
+
+	var (
+		text      []byte
+		pagePos   = make([]int, 0, len(b.Pages))
+		selectors = make([]string, 0, len(b.Pages))
+	)
+	for i, page := range b.Pages {
+		start := strings.Index(page, "<body")
+		if start < 0 {
+			return nil, skel, frag, nil, fmt.Errorf("azw3: page %d has no body", i)
+		}
+		end := strings.Index(page[start:], ">")
+		closing := strings.LastIndex(page, "</body>")
+		if end < 0 || closing < start+end {
+			return nil, skel, frag, nil, fmt.Errorf("azw3: page %d has an invalid body", i)
+		}
+		end += start
+
+		aid := base32(i, 1)
+		bodyTag := strings.TrimSuffix(page[start:end], "/") + ` aid="` + aid + `">`
+		skeleton := page[:start] + bodyTag + page[closing:]
+		fragment := page[end+1 : closing]
+
+		pos := len(text)
+		pagePos = append(pagePos, pos)
+		skel.entries = append(skel.entries, indexEntry{
+			key: fmt.Sprintf("SKEL%010d", i),
+			values: map[byte][]uint32{
+				1: {1, 1},
+				6: {uint32(pos), uint32(len(skeleton)), uint32(pos), uint32(len(skeleton))},
+			},
+		})
+		frag.entries = append(frag.entries, indexEntry{
+			key: fmt.Sprintf("%010d", pos+start+len(bodyTag)),
+			values: map[byte][]uint32{
+				3: {uint32(i)},
+				4: {uint32(i)},
+				6: {0, uint32(len(fragment))},
+			},
+		})
+		selectors = append(selectors, `P-//*[@aid='`+aid+`']`)
+		text = append(text, skeleton...)
+		text = append(text, fragment...)
+	}
+
+	cncx, offsets := newCNCX(selectors)
+	frag.cncx = cncx
+	for i := range frag.entries {
+		frag.entries[i].values[2] = []uint32{offsets[i]}
+	}
+
+	return text, skel, frag, pagePos, nil
+}
+
+// split the text into records.
+//
+// a character split between 2 records is completed at the end of the first one,
+// followed by the number of bytes added (multibyte trailing entry).
+func (b Book) textRecords(text []byte) [][]byte {
+	var records [][]byte
+	for pos := 0; pos < len(text); pos += textRecordSize {
+		end := min(pos+textRecordSize, len(text))
+		r := append([]byte{}, text[pos:end]...)
+		overlap := 0
+		for end+overlap < len(text) && overlap < 3 && text[end+overlap]&0xc0 == 0x80 {
+			overlap++
+		}
+		r = append(r, text[end:end+overlap]...)
+		records = append(records, append(r, byte(overlap)))
+	}
+	return records
+}
+
+// table of content, the chapters are flat
+func (b Book) ncx(textLength int, pagePos []int) (index, bool) {
+	if len(b.Chapters) == 0 {
+		return index{}, false
+	}
+	ncx := index{tags: []tagMeta{
+		{1, 1, 1, 0},
+		{2, 1, 2, 0},
+		{3, 1, 4, 0},
+		{4, 1, 8, 0},
+		{21, 1, 16, 0},
+		{22, 1, 32, 0},
+		{23, 1, 64, 0},
+		{6, 2, 128, 0},
+		endTagTable,
+		{16, 1, 1, 0},
+		{17, 1, 2, 0},
+		{18, 1, 4, 0},
+		{19, 1, 8, 0},
+		{20, 1, 16, 0},
+		endTagTable,
+	}}
+
+	labels := make([]string, len(b.Chapters))
+	for i, c := range b.Chapters {
+		labels[i] = c.Title
+	}
+	var offsets []uint32
+	ncx.cncx, offsets = newCNCX(labels)
+
+	keyFmt := fmt.Sprintf("%%0%dX", len(fmt.Sprintf("%X", len(b.Chapters)-1)))
+	for i, c := range b.Chapters {
+		start := pagePos[c.Page]
+		end := textLength
+		if i+1 < len(b.Chapters) {
+			end = pagePos[b.Chapters[i+1].Page]
+		}
+		ncx.entries = append(ncx.entries, indexEntry{
+			key: fmt.Sprintf(keyFmt, i),
+			values: map[byte][]uint32{
+				1: {uint32(start)},
+				2: {uint32(end - start)},
+				3: {offsets[i]},
+				4: {0},
+				6: {uint32(c.Page), 0},
+			},
+		})
+	}
+	return ncx, true
+}
+
+type record0Options struct {
+	textLength, textRecords     int
+	firstNonText, firstResource uint32
+	fdst, fcis, flis            uint32
+	ncx, frag, skel             uint32
+}
+
+// record 0: PalmDOC header, MOBI header, EXTH metadata and the full title
+func (b Book) record0(o record0Options) []byte {
+	const mobiHeaderLength = 264
+
+	exth := b.exth()
+	title := []byte(b.Title)
+	titleOffset := 16 + mobiHeaderLength + len(exth)
+
+	var r bytes.Buffer
+	put := func(values ...any) {
+		for _, v := range values {
+			_ = binary.Write(&r, binary.BigEndian, v)
+		}
+	}
+
+	// PalmDOC header, the text isn't compressed
+	put(uint16(1), uint16(0), uint32(o.textLength), uint16(o.textRecords), uint16(textRecordSize), uint16(0), uint16(0))
+
+	// MOBI header
+	r.WriteString("MOBI")
+	put(uint32(mobiHeaderLength), uint32(2), uint32(65001), crc32.ChecksumIEEE([]byte(b.UID)), uint32(8))
+	for range 10 {
+		// orthographic, inflection, names, keys and extra indexes
+		put(uint32(nullIndex))
+	}
+	put(o.firstNonText, uint32(titleOffset), uint32(len(title)), uint32(0), uint32(0), uint32(0), uint32(8), o.firstResource)
+	r.Write(make([]byte, 16)) // huffman
+	put(uint32(0x50))         // EXTH flags
+	r.Write(make([]byte, 32))
+	put(uint32(nullIndex), uint32(nullIndex), uint32(0), uint32(0), uint32(0)) // DRM
+	r.Write(make([]byte, 8))
+	put(o.fdst, uint32(2), o.fcis, uint32(1), o.flis, uint32(1))
+	r.Write(make([]byte, 8))
+	put(uint32(nullIndex), uint32(0), uint32(nullIndex), uint32(nullIndex))
+	// extra data flags: multibyte trailing entry
+	put(uint32(1), o.ncx, o.frag, o.skel, uint32(nullIndex), uint32(nullIndex))
+	put(uint32(nullIndex), uint32(0), uint32(nullIndex), uint32(0))
+
+	r.Write(exth)
+	r.Write(title)
+	r.Write([]byte{0, 0})
+	return align(r.Bytes())
+}
+
+// EXTH records of the OPF metadata
+var exthCodes = map[string]uint32{
+	"dc:creator":            100,
+	"dc:publisher":          101,
+	"dc:description":        103,
+	"dc:subject":            105,
+	"dc:date":               106,
+	"dc:contributor":        108,
+	"fixed-layout":          122,
+	"book-type":             123,
+	"rendition:orientation": 124,
+	"original-resolution":   126,
+	"dc:title":              503,
+	"dc:language":           524,
+	"primary-writing-mode":  525,
+}
+
+func (b Book) exth() []byte {
+	var records [][]byte
+	add := func(code uint32, data []byte) {
+		r := binary.BigEndian.AppendUint32(nil, code)
+		r = binary.BigEndian.AppendUint32(r, uint32(8+len(data)))
+		records = append(records, append(r, data...))
+	}
+	addString := func(code uint32, s string) {
+		add(code, []byte(s))
+	}
+	addInt := func(code uint32, v uint32) {
+		add(code, binary.BigEndian.AppendUint32(nil, v))
+	}
+
+	for _, m := range b.Meta {
+		code, ok := exthCodes[m.Name]
+		if !ok || m.Value == "" {
+			continue
+		}
+		value := m.Value
+		// the orientation is locked only in portrait
+		if code == 124 && value == "auto" {
+			value = "none"
+		}
+		addString(code, value)
+	}
+	if b.RightToLeft {
+		addString(527, "rtl")
+	}
+
+	// sideloaded books display their cover only as ebook with an ASIN
+	addString(113, b.UID)
+	addString(504, b.UID)
+	addString(501, "EBOK")
+	addInt(125, uint32(len(b.Resources)))
+	if b.CoverId >= 0 {
+		addInt(201, uint32(b.CoverId))
+		addInt(203, 0)
+		addString(129, EmbedURL(b.CoverId, b.Resources[b.CoverId].Mime))
+	}
+	// creator: kindlegen 2.9
+	addInt(204, 202)
+	addInt(205, 2)
+	addInt(206, 9)
+	addInt(207, 0)
+
+	size := 12
+	for _, r := range records {
+		size += len(r)
+	}
+	e := []byte("EXTH")
+	e = binary.BigEndian.AppendUint32(e, uint32(size))
+	e = binary.BigEndian.AppendUint32(e, uint32(len(records)))
+	for _, r := range records {
+		e = append(e, r...)
+	}
+	return align(e)
+}
+
+func flis() []byte {
+	return []byte{
+		'F', 'L', 'I', 'S', 0, 0, 0, 8, 0, 0x41, 0, 0, 0, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0, 1, 0, 3, 0, 0, 0, 3, 0, 0, 0, 1,
+		0xff, 0xff, 0xff, 0xff,
+	}
+}
+
+func fcis(textLength int) []byte {
+	r := []byte{'F', 'C', 'I', 'S', 0, 0, 0, 0x14, 0, 0, 0, 0x10, 0, 0, 0, 2, 0, 0, 0, 0}
+	r = binary.BigEndian.AppendUint32(r, uint32(textLength))
+	return append(r,
+		0, 0, 0, 0, 0, 0, 0, 0x28, 0, 0, 0, 0, 0, 0, 0,
+		0x28, 0, 0, 0, 8, 0, 1, 0, 1, 0, 0, 0, 0,
+	)
+}
+
+// name of the database: ascii only, 31 characters max
+func (b Book) databaseName() []byte {
+	name := make([]byte, 32)
+	i := 0
+	for _, c := range b.Title {
+		if i == 31 {
+			break
+		}
+		switch {
+		case c == ' ':
+			name[i] = '_'
+		case c > ' ' && c < 0x7f:
+			name[i] = byte(c)
+		default:
+			continue
+		}
+		i++
+	}
+	return name
+}
+
+// write the palm database header, the list of records, then the records
+func (b Book) writeDatabase(w io.Writer, records [][]byte) error {
+	var h bytes.Buffer
+	now := uint32(time.Now().Unix())
+	h.Write(b.databaseName())
+	for _, v := range []any{
+		uint16(0), uint16(0), // attributes, version
+		now, now, uint32(0), // creation, modification, backup
+		uint32(0), uint32(0), uint32(0), // modification number, app info, sort info
+	} {
+		_ = binary.Write(&h, binary.BigEndian, v)
+	}
+	h.WriteString("BOOKMOBI")
+	for _, v := range []any{uint32(2*len(records) - 1), uint32(0), uint16(len(records))} {
+		_ = binary.Write(&h, binary.BigEndian, v)
+	}
+
+	offset := h.Len() + 8*len(records) + 2
+	for i, r := range records {
+		_ = binary.Write(&h, binary.BigEndian, uint32(offset))
+		_ = binary.Write(&h, binary.BigEndian, uint32(2*i)&0xffffff)
+		offset += len(r)
+	}
+	h.Write([]byte{0, 0})
+
+	if _, err := w.Write(h.Bytes()); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if _, err := w.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
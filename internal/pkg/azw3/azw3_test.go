@@ -0,0 +1,487 @@
+package azw3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// minimal reader of the palm database written by the book, enough to check the structure
+type reader struct {
+	t       *testing.T
+	records [][]byte
+}
+
+func read(t *testing.T, b Book) *reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data := buf.Bytes()
+
+	if got := string(data[60:68]); got != "BOOKMOBI" {
+		t.Fatalf("type and creator: got %q", got)
+	}
+	n := int(binary.BigEndian.Uint16(data[76:]))
+	if next := binary.BigEndian.Uint32(data[68:]); next != uint32(2*n-1) {
+		t.Errorf("next unique id: got %d, want %d", next, 2*n-1)
+	}
+	offsets := make([]int, n+1)
+	for i := range n {
+		offsets[i] = int(binary.BigEndian.Uint32(data[78+8*i:]))
+		if id := binary.BigEndian.Uint32(data[82+8*i:]) & 0xffffff; id != uint32(2*i) {
+			t.Errorf("record %d: unique id %d, want %d", i, id, 2*i)
+		}
+	}
+	offsets[n] = len(data)
+	if offsets[0] != 78+8*n+2 {
+		t.Errorf("first record at %d, want %d", offsets[0], 78+8*n+2)
+	}
+
+	r := &reader{t: t}
+	for i := range n {
+		if offsets[i] > offsets[i+1] {
+			t.Fatalf("record %d: offset %d after the next one %d", i, offsets[i], offsets[i+1])
+		}
+		r.records = append(r.records, data[offsets[i]:offsets[i+1]])
+	}
+	return r
+}
+
+func (r *reader) u32(record, offset int) uint32 {
+	return binary.BigEndian.Uint32(r.records[record][offset:])
+}
+
+// record 0 fields, offsets from the start of the record
+func (r *reader) textLength() int    { return int(r.u32(0, 4)) }
+func (r *reader) textRecords() int   { return int(binary.BigEndian.Uint16(r.records[0][8:])) }
+func (r *reader) firstResource() int { return int(r.u32(0, 108)) }
+func (r *reader) fdst() int          { return int(r.u32(0, 192)) }
+func (r *reader) ncx() uint32        { return r.u32(0, 244) }
+func (r *reader) frag() int          { return int(r.u32(0, 248)) }
+func (r *reader) skel() int          { return int(r.u32(0, 252)) }
+
+// EXTH records by code
+func (r *reader) exth() map[uint32][][]byte {
+	r0 := r.records[0]
+	e := r0[16+int(r.u32(0, 20)):]
+	if string(e[:4]) != "EXTH" {
+		r.t.Fatalf("exth: got %q", e[:4])
+	}
+	values := map[uint32][][]byte{}
+	count := int(binary.BigEndian.Uint32(e[8:]))
+	p := 12
+	for range count {
+		code, size := binary.BigEndian.Uint32(e[p:]), int(binary.BigEndian.Uint32(e[p+4:]))
+		values[code] = append(values[code], e[p+8:p+size])
+		p += size
+	}
+	if size := int(binary.BigEndian.Uint32(e[4:])); size != p {
+		r.t.Errorf("exth: size %d, want %d", size, p)
+	}
+	return values
+}
+
+// text of the book, without the trailing entries
+func (r *reader) text() []byte {
+	var text []byte
+	for i := 1; i <= r.textRecords(); i++ {
+		rec := r.records[i]
+		overlap := int(rec[len(rec)-1] & 3)
+		text = append(text, rec[:len(rec)-1-overlap]...)
+	}
+	return text
+}
+
+func decint(b []byte) (uint32, int) {
+	var v uint32
+	for i, c := range b {
+		v = v<<7 | uint32(c&0x7f)
+		if c&0x80 != 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+type readEntry struct {
+	key    string
+	values map[byte][]uint32
+}
+
+// entries of the index starting at the record, and the number of strings records
+func (r *reader) index(start int) ([]readEntry, int) {
+	h := r.records[start]
+	if string(h[:4]) != "INDX" {
+		r.t.Fatalf("record %d: got %q, want INDX", start, h[:4])
+	}
+	dataRecords, total, cncx := int(r.u32(start, 24)), int(r.u32(start, 36)), int(r.u32(start, 52))
+
+	tagx := h[r.u32(start, 180):]
+	if string(tagx[:4]) != "TAGX" {
+		r.t.Fatalf("record %d: got %q, want TAGX", start, tagx[:4])
+	}
+	controlBytes := int(binary.BigEndian.Uint32(tagx[8:]))
+	var tags []tagMeta
+	for p := 12; p < int(binary.BigEndian.Uint32(tagx[4:])); p += 4 {
+		tags = append(tags, tagMeta{tagx[p], tagx[p+1], tagx[p+2], tagx[p+3]})
+	}
+
+	var entries []readEntry
+	for i := 1; i <= dataRecords; i++ {
+		d := r.records[start+i]
+		idxt, count := int(r.u32(start+i, 20)), int(r.u32(start+i, 24))
+		for j := range count {
+			p := int(binary.BigEndian.Uint16(d[idxt+4+2*j:]))
+			e := readEntry{key: string(d[p+1 : p+1+int(d[p])]), values: map[byte][]uint32{}}
+			p += 1 + int(d[p])
+			control := d[p : p+controlBytes]
+			p += controlBytes
+			cb := 0
+			for _, t := range tags {
+				if t.endFlag == 1 {
+					cb++
+					continue
+				}
+				n := int(control[cb]&t.mask) >> bits.TrailingZeros8(t.mask)
+				for range n * int(t.valuesPerEntry) {
+					v, size := decint(d[p:])
+					e.values[t.tag] = append(e.values[t.tag], v)
+					p += size
+				}
+			}
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) != total {
+		r.t.Errorf("index %d: %d entries, header count %d", start, len(entries), total)
+	}
+	return entries, cncx
+}
+
+// string of the cncx of an index
+func (r *reader) cncx(start int, offset uint32) string {
+	entries := int(r.u32(start, 24))
+	rec := r.records[start+1+entries+int(offset>>16)]
+	length, size := decint(rec[offset&0xffff:])
+	p := int(offset&0xffff) + size
+	return string(rec[p : p+int(length)])
+}
+
+// pages rebuilt from the skeletons and the fragments
+func (r *reader) pages() []string {
+	text := r.text()
+	skel, _ := r.index(r.skel())
+	frag, _ := r.index(r.frag())
+	pages := make([]string, len(skel))
+	for i, s := range skel {
+		pos, length := s.values[6][0], s.values[6][1]
+		skeleton := string(text[pos : pos+length])
+		f := frag[i]
+		var insert uint32
+		_, _ = fmt.Sscanf(f.key, "%d", &insert)
+		at := insert - pos
+		fragment := string(text[pos+length : pos+length+f.values[6][1]])
+		pages[i] = skeleton[:at] + fragment + skeleton[at:]
+	}
+	return pages
+}
+
+func page(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<html xmlns="http://www.w3.org/1999/xhtml"><head><link href="` + FlowURL() + `" rel="stylesheet" type="text/css"/></head>` +
+		`<body class="page">` + body + `</body></html>`
+}
+
+func testBook() Book {
+	return Book{
+		UID:   "urn:uuid:00000000-0000-0000-0000-000000000001",
+		Title: "Été Vol.1 #2: The Title",
+		Meta: []Meta{
+			{"dc:creator", "Author"},
+			{"dc:language", "fr"},
+			{"fixed-layout", "true"},
+			{"rendition:orientation", "auto"},
+		},
+		RightToLeft: true,
+		CSS:         "body{margin:0}",
+		Pages: []string{
+			page(`<div><img src="` + EmbedURL(0, "image/jpeg") + `"/></div>`),
+			// a multibyte character is split between the first 2 text records
+			page(`<p>` + strings.Repeat("é", 3000) + `</p>`),
+			page(`<div><img src="` + EmbedURL(1, "image/png") + `"/></div>`),
+		},
+		Resources: []Resource{
+			{[]byte("jpeg data"), "image/jpeg"},
+			{[]byte("png data"), "image/png"},
+		},
+		CoverId:  0,
+		Chapters: []Chapter{{"Chapter 1", 0}, {"Chapter 2", 2}},
+	}
+}
+
+func TestDatabaseRecords(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		book      Book
+		resources int
+		ncx       bool
+	}{
+		{"full", testBook(), 2, true},
+		{"no chapter", func() Book { b := testBook(); b.Chapters = nil; return b }(), 2, false},
+		{"no resource", Book{UID: "u", Title: "t", Pages: []string{page("")}, CoverId: -1}, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := read(t, tc.book)
+
+			// record 0, text, frag with its strings, skel, ncx, resources, fdst, flis, fcis, eof
+			wantRecords := 1 + r.textRecords() + 3 + 2 + len(tc.book.Resources) + 4
+			if tc.ncx {
+				wantRecords += 3
+			}
+			if len(r.records) != wantRecords {
+				t.Errorf("records: got %d, want %d", len(r.records), wantRecords)
+			}
+			for i, rec := range r.records[1 : 1+r.textRecords()] {
+				if len(rec) > textRecordSize+4 {
+					t.Errorf("text record %d: %d bytes", i, len(rec))
+				}
+			}
+			if !bytes.Equal(r.records[len(r.records)-1], []byte{0xe9, 0x8e, 0x0d, 0x0a}) {
+				t.Errorf("eof record: got %x", r.records[len(r.records)-1])
+			}
+			if tc.resources > 0 {
+				for i, res := range tc.book.Resources {
+					if got := r.records[r.firstResource()+i]; !bytes.Equal(got, res.Data) {
+						t.Errorf("resource %d: got %q", i, got)
+					}
+				}
+			} else if r.firstResource() != nullIndex {
+				t.Errorf("first resource: got %d, want none", r.firstResource())
+			}
+			if (r.ncx() != nullIndex) != tc.ncx {
+				t.Errorf("ncx: got %d", r.ncx())
+			}
+			if title := r.records[0][r.u32(0, 84):][:r.u32(0, 88)]; string(title) != tc.book.Title {
+				t.Errorf("title: got %q", title)
+			}
+		})
+	}
+}
+
+func TestEXTH(t *testing.T) {
+	withCover := func(id int) Book {
+		b := testBook()
+		b.CoverId = id
+		return b
+	}
+	for _, tc := range []struct {
+		name  string
+		book  Book
+		cover int
+	}{
+		{"first cover", withCover(0), 0},
+		{"last cover", withCover(1), 1},
+		{"no cover", withCover(-1), -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := read(t, tc.book)
+			exth := r.exth()
+
+			// the book is KF8 only, there is no boundary with a mobi 6 part
+			if v, ok := exth[121]; ok {
+				t.Errorf("121: got %x, want none", v)
+			}
+			if v := exth[125]; len(v) != 1 || binary.BigEndian.Uint32(v[0]) != uint32(len(tc.book.Resources)) {
+				t.Errorf("125: got %x, want %d", v, len(tc.book.Resources))
+			}
+			v, ok := exth[201]
+			switch {
+			case tc.cover < 0 && ok:
+				t.Errorf("201: got %x, want none", v)
+			case tc.cover >= 0 && (len(v) != 1 || binary.BigEndian.Uint32(v[0]) != uint32(tc.cover)):
+				t.Errorf("201: got %x, want %d", v, tc.cover)
+			case tc.cover >= 0:
+				if got, want := string(exth[129][0]), EmbedURL(tc.cover, tc.book.Resources[tc.cover].Mime); got != want {
+					t.Errorf("129: got %q, want %q", got, want)
+				}
+			}
+			if got := string(exth[527][0]); got != "rtl" {
+				t.Errorf("527: got %q", got)
+			}
+			if got := string(exth[124][0]); got != "none" {
+				t.Errorf("124: got %q, the orientation auto is not locked", got)
+			}
+		})
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	manyPages := Book{UID: "u", Title: "t", CoverId: -1}
+	for i := range 2000 {
+		manyPages.Pages = append(manyPages.Pages, page(fmt.Sprintf("<p>%d</p>", i)))
+		manyPages.Chapters = append(manyPages.Chapters, Chapter{fmt.Sprintf("Chapter %d", i), i})
+	}
+	for _, tc := range []struct {
+		name string
+		book Book
+	}{
+		{"book", testBook()},
+		// the entries don't fit in one index record
+		{"many pages", manyPages},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := read(t, tc.book)
+			text := r.text()
+
+			skel, _ := r.index(r.skel())
+			frag, cncx := r.index(r.frag())
+			if len(skel) != len(tc.book.Pages) || len(frag) != len(tc.book.Pages) {
+				t.Fatalf("entries: %d skeletons, %d fragments, want %d", len(skel), len(frag), len(tc.book.Pages))
+			}
+			if cncx == 0 {
+				t.Errorf("fragments: no cncx record")
+			}
+			for i := range tc.book.Pages {
+				if want := fmt.Sprintf("SKEL%010d", i); skel[i].key != want {
+					t.Errorf("skeleton %d: key %q, want %q", i, skel[i].key, want)
+				}
+				if got, want := r.cncx(r.frag(), frag[i].values[2][0]), fmt.Sprintf("P-//*[@aid='%s']", base32(i, 1)); got != want {
+					t.Errorf("fragment %d: selector %q, want %q", i, got, want)
+				}
+			}
+
+			ncx, _ := r.index(int(r.ncx()))
+			if len(ncx) != len(tc.book.Chapters) {
+				t.Fatalf("ncx: %d entries, want %d", len(ncx), len(tc.book.Chapters))
+			}
+			end := uint32(len(text) - len(tc.book.CSS))
+			for i := len(ncx) - 1; i >= 0; i-- {
+				c := tc.book.Chapters[i]
+				start := skel[c.Page].values[6][0]
+				if got := ncx[i].values[1][0]; got != start {
+					t.Errorf("chapter %d: start %d, want %d", i, got, start)
+				}
+				if got := ncx[i].values[2][0]; got != end-start {
+					t.Errorf("chapter %d: length %d, want %d", i, got, end-start)
+				}
+				if got := r.cncx(int(r.ncx()), ncx[i].values[3][0]); got != c.Title {
+					t.Errorf("chapter %d: label %q, want %q", i, got, c.Title)
+				}
+				end = start
+			}
+		})
+	}
+}
+
+func TestFDST(t *testing.T) {
+	for _, css := range []string{"body{margin:0}", ""} {
+		t.Run(fmt.Sprintf("css %q", css), func(t *testing.T) {
+			b := testBook()
+			b.CSS = css
+			r := read(t, b)
+			text := r.text()
+			if len(text) != r.textLength() {
+				t.Fatalf("text: %d bytes, header %d", len(text), r.textLength())
+			}
+
+			f := r.records[r.fdst()]
+			if string(f[:4]) != "FDST" || binary.BigEndian.Uint32(f[4:]) != 12 {
+				t.Fatalf("fdst header: got %x", f[:12])
+			}
+			if n := binary.BigEndian.Uint32(f[8:]); n != 2 {
+				t.Fatalf("fdst: %d sections, want 2", n)
+			}
+			var ranges [2][2]uint32
+			for i := range ranges {
+				ranges[i] = [2]uint32{binary.BigEndian.Uint32(f[12+8*i:]), binary.BigEndian.Uint32(f[16+8*i:])}
+			}
+			html := uint32(len(text) - len(css))
+			if want := [2][2]uint32{{0, html}, {html, uint32(len(text))}}; ranges != want {
+				t.Errorf("ranges: got %v, want %v", ranges, want)
+			}
+			if got := string(text[ranges[1][0]:ranges[1][1]]); got != css {
+				t.Errorf("css flow: got %q", got)
+			}
+		})
+	}
+}
+
+// the book is read back and compared to the fixture
+func TestRoundTrip(t *testing.T) {
+	b := testBook()
+	r := read(t, b)
+
+	pages := r.pages()
+	for i, p := range b.Pages {
+		want := strings.Replace(p, `<body class="page">`, `<body class="page" aid="`+base32(i, 1)+`">`, 1)
+		if pages[i] != want {
+			t.Errorf("page %d: got\n%s\nwant\n%s", i, pages[i], want)
+		}
+	}
+
+	var dump strings.Builder
+	fmt.Fprintf(&dump, "records: %d\n", len(r.records))
+	fmt.Fprintf(&dump, "text: %d bytes in %d records\n", r.textLength(), r.textRecords())
+	exth := r.exth()
+	codes := make([]int, 0, len(exth))
+	for code := range exth {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		for _, v := range exth[uint32(code)] {
+			switch code {
+			case 125, 201, 203, 204, 205, 206, 207:
+				fmt.Fprintf(&dump, "exth %d: %d\n", code, binary.BigEndian.Uint32(v))
+			default:
+				fmt.Fprintf(&dump, "exth %d: %q\n", code, v)
+			}
+		}
+	}
+	for _, idx := range []struct {
+		name  string
+		start int
+	}{{"skel", r.skel()}, {"frag", r.frag()}, {"ncx", int(r.ncx())}} {
+		entries, _ := r.index(idx.start)
+		for _, e := range entries {
+			tags := make([]int, 0, len(e.values))
+			for tag := range e.values {
+				tags = append(tags, int(tag))
+			}
+			sort.Ints(tags)
+			fmt.Fprintf(&dump, "%s %s:", idx.name, e.key)
+			for _, tag := range tags {
+				fmt.Fprintf(&dump, " %d=%v", tag, e.values[byte(tag)])
+			}
+			fmt.Fprintln(&dump)
+		}
+	}
+	for i, p := range pages {
+		fmt.Fprintf(&dump, "page %d: %s\n", i, strings.ReplaceAll(p, strings.Repeat("é", 3000), "é×3000"))
+	}
+
+	golden := filepath.Join("testdata", "book.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(dump.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.String() != string(want) {
+		t.Errorf("book differs from %s, got:\n%s", golden, dump.String())
+	}
+}
@@ -0,0 +1,203 @@
+package azw3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	indexHeaderLength = 192
+	// an index record is addressed with 16 bits offsets, keep some room like kindlegen
+	indexRecordLimit = 0x10000 - 1024
+)
+
+// tag of an index entry, described in the TAGX section
+type tagMeta struct {
+	tag            byte
+	valuesPerEntry byte
+	mask           byte
+	endFlag        byte
+}
+
+var endTagTable = tagMeta{0, 0, 0, 1}
+
+// entry of an index, the values are stored by tag
+type indexEntry struct {
+	key    string
+	values map[byte][]uint32
+}
+
+type index struct {
+	tags    []tagMeta
+	entries []indexEntry
+	cncx    [][]byte
+}
+
+// forward variable width integer: 7 bits per byte, the last byte has the high bit set
+func encint(v uint32) []byte {
+	b := []byte{byte(v&0x7f) | 0x80}
+	for v >>= 7; v > 0; v >>= 7 {
+		b = append([]byte{byte(v & 0x7f)}, b...)
+	}
+	return b
+}
+
+// pad the block to a 4 bytes boundary
+func align(b []byte) []byte {
+	if r := len(b) % 4; r > 0 {
+		b = append(b, make([]byte, 4-r)...)
+	}
+	return b
+}
+
+// newCNCX store the strings of an index, it returns the offset of each string.
+//
+// the record number is stored in the high bits of the offset.
+func newCNCX(strings []string) ([][]byte, []uint32) {
+	var (
+		records [][]byte
+		offsets = make([]uint32, len(strings))
+		buf     []byte
+	)
+	for i, s := range strings {
+		raw := append(encint(uint32(len(s))), s...)
+		if len(buf)+len(raw) > indexRecordLimit {
+			records = append(records, align(buf))
+			buf = nil
+		}
+		offsets[i] = uint32(len(records))<<16 | uint32(len(buf))
+		buf = append(buf, raw...)
+	}
+	if len(buf) > 0 {
+		records = append(records, align(buf))
+	}
+	return records, offsets
+}
+
+func (x index) tagx() []byte {
+	controlBytes := 0
+	for _, t := range x.tags {
+		if t.endFlag == 1 {
+			controlBytes++
+		}
+	}
+	b := []byte("TAGX")
+	b = binary.BigEndian.AppendUint32(b, uint32(12+4*len(x.tags)))
+	b = binary.BigEndian.AppendUint32(b, uint32(controlBytes))
+	for _, t := range x.tags {
+		b = append(b, t.tag, t.valuesPerEntry, t.mask, t.endFlag)
+	}
+	return b
+}
+
+// encode an entry: key, control bytes, then the values
+func (x index) encodeEntry(e indexEntry) []byte {
+	b := append([]byte{byte(len(e.key))}, e.key...)
+	var control byte
+	for _, t := range x.tags {
+		if t.endFlag == 1 {
+			b = append(b, control)
+			control = 0
+			continue
+		}
+		n := len(e.values[t.tag]) / int(t.valuesPerEntry)
+		control |= t.mask & byte(n<<bits.TrailingZeros8(t.mask))
+	}
+	for _, t := range x.tags {
+		for _, v := range e.values[t.tag] {
+			b = append(b, encint(v)...)
+		}
+	}
+	return b
+}
+
+// records of the index: the header, the entries, then the strings
+func (x index) records() [][]byte {
+	type dataRecord struct {
+		block   []byte
+		offsets []int
+		lastKey string
+	}
+
+	var (
+		data    []dataRecord
+		current dataRecord
+	)
+	for _, e := range x.entries {
+		raw := x.encodeEntry(e)
+		if len(current.offsets) > 0 && indexHeaderLength+len(current.block)+len(raw)+4+2*(len(current.offsets)+1) > indexRecordLimit {
+			data = append(data, current)
+			current = dataRecord{}
+		}
+		current.offsets = append(current.offsets, len(current.block))
+		current.block = append(current.block, raw...)
+		current.lastKey = e.key
+	}
+	data = append(data, current)
+
+	records := make([][]byte, 0, 1+len(data)+len(x.cncx))
+
+	// header: geometry of the data records
+	tagx := x.tagx()
+	var geometry []byte
+	geometryOffsets := make([]int, 0, len(data))
+	for _, d := range data {
+		geometryOffsets = append(geometryOffsets, indexHeaderLength+len(tagx)+len(geometry))
+		geometry = append(geometry, byte(len(d.lastKey)))
+		geometry = append(geometry, d.lastKey...)
+		geometry = binary.BigEndian.AppendUint16(geometry, uint16(len(d.offsets)))
+	}
+	geometry = align(geometry)
+
+	var header bytes.Buffer
+	header.WriteString("INDX")
+	for _, v := range []uint32{
+		indexHeaderLength, 0, 0, 2,
+		uint32(indexHeaderLength + len(tagx) + len(geometry)), // IDXT
+		uint32(len(data)),
+		65001,
+		0xffffffff,
+		uint32(len(x.entries)),
+		0, 0, 0,
+		uint32(len(x.cncx)),
+	} {
+		_ = binary.Write(&header, binary.BigEndian, v)
+	}
+	header.Write(make([]byte, 124))
+	_ = binary.Write(&header, binary.BigEndian, uint32(indexHeaderLength)) // TAGX
+	header.Write(make([]byte, 8))
+	header.Write(tagx)
+	header.Write(geometry)
+	idxt := []byte("IDXT")
+	for _, o := range geometryOffsets {
+		idxt = binary.BigEndian.AppendUint16(idxt, uint16(o))
+	}
+	header.Write(align(idxt))
+	records = append(records, header.Bytes())
+
+	// entries
+	for _, d := range data {
+		block := align(d.block)
+		var r bytes.Buffer
+		r.WriteString("INDX")
+		for _, v := range []uint32{
+			indexHeaderLength, 0, 1, 0,
+			uint32(indexHeaderLength + len(block)), // IDXT
+			uint32(len(d.offsets)),
+			0xffffffff, 0xffffffff,
+		} {
+			_ = binary.Write(&r, binary.BigEndian, v)
+		}
+		r.Write(make([]byte, indexHeaderLength-r.Len()))
+		r.Write(block)
+		idxt := []byte("IDXT")
+		for _, o := range d.offsets {
+			idxt = binary.BigEndian.AppendUint16(idxt, uint16(indexHeaderLength+o))
+		}
+		r.Write(align(idxt))
+		records = append(records, r.Bytes())
+	}
+
+	return append(records, x.cncx...)
+}
@@ -0,0 +1,181 @@
+/*
+Package comicinfo read the ComicInfo.xml metadata included with comics.
+
+The format is described by the Anansi Project:
+https://anansi-project.github.io/docs/comicinfo/intro
+*/
+package comicinfo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+type ComicInfo struct {
+	Title       string `xml:"Title,omitempty" json:"title,omitempty"`
+	Series      string `xml:"Series,omitempty" json:"series,omitempty"`
+	Number      string `xml:"Number,omitempty" json:"number,omitempty"`
+	Volume      int    `xml:"Volume,omitempty" json:"volume,omitempty"`
+	Summary     string `xml:"Summary,omitempty" json:"summary,omitempty"`
+	Year        int    `xml:"Year,omitempty" json:"year,omitempty"`
+	Month       int    `xml:"Month,omitempty" json:"month,omitempty"`
+	Day         int    `xml:"Day,omitempty" json:"day,omitempty"`
+	Writer      string `xml:"Writer,omitempty" json:"writer,omitempty"`
+	Penciller   string `xml:"Penciller,omitempty" json:"penciller,omitempty"`
+	Inker       string `xml:"Inker,omitempty" json:"inker,omitempty"`
+	Colorist    string `xml:"Colorist,omitempty" json:"colorist,omitempty"`
+	Letterer    string `xml:"Letterer,omitempty" json:"letterer,omitempty"`
+	CoverArtist string `xml:"CoverArtist,omitempty" json:"cover_artist,omitempty"`
+	Editor      string `xml:"Editor,omitempty" json:"editor,omitempty"`
+	Publisher   string `xml:"Publisher,omitempty" json:"publisher,omitempty"`
+	Genre       string `xml:"Genre,omitempty" json:"genre,omitempty"`
+	LanguageISO string `xml:"LanguageISO,omitempty" json:"language_iso,omitempty"`
+	Manga       string `xml:"Manga,omitempty" json:"manga,omitempty"`
+	PageCount   int    `xml:"PageCount,omitempty" json:"page_count,omitempty"`
+	Pages       []Page `xml:"Pages>Page" json:"pages,omitempty"`
+}
+
+// Page annotation of an image, the index is the position of the image in the comic
+type Page struct {
+	Image       int    `xml:"Image,attr" json:"image"`
+	Type        string `xml:"Type,attr,omitempty" json:"type,omitempty"`
+	DoublePage  bool   `xml:"DoublePage,attr,omitempty" json:"double_page,omitempty"`
+	ImageWidth  int    `xml:"ImageWidth,attr,omitempty" json:"image_width,omitempty"`
+	ImageHeight int    `xml:"ImageHeight,attr,omitempty" json:"image_height,omitempty"`
+	Bookmark    string `xml:"Bookmark,attr,omitempty" json:"bookmark,omitempty"`
+}
+
+// HasType check the type of the page, a page can have multiple types separated by a space
+func (p Page) HasType(t string) bool {
+	for _, v := range strings.Fields(p.Type) {
+		if strings.EqualFold(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contributor person and his role using the MARC relator code
+type Contributor struct {
+	Name string
+	Role string
+}
+
+// Parse ComicInfo.xml
+func Parse(r io.Reader) (ComicInfo, error) {
+	var c ComicInfo
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		return ComicInfo{}, err
+	}
+	return c, nil
+}
+
+// XML ComicInfo.xml content
+func (c ComicInfo) XML() ([]byte, error) {
+	b, err := xml.MarshalIndent(struct {
+		XMLName xml.Name `xml:"ComicInfo"`
+		ComicInfo
+	}{ComicInfo: c}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// IsComicInfo check the filename of ComicInfo.xml, the case doesn't matter
+func IsComicInfo(filename string) bool {
+	i := strings.LastIndexAny(filename, `/\`)
+	return strings.EqualFold(filename[i+1:], "ComicInfo.xml")
+}
+
+// IsEmpty no metadata
+func (c ComicInfo) IsEmpty() bool {
+	return reflect.DeepEqual(c, ComicInfo{})
+}
+
+// IsRightToLeft manga read from right to left
+func (c ComicInfo) IsRightToLeft() bool {
+	return c.Manga == "YesAndRightToLeft"
+}
+
+// FullTitle title including the series, the volume and the number
+//
+// Example: Series Vol.2 #12: Title
+func (c ComicInfo) FullTitle() string {
+	if c.Series == "" {
+		return c.Title
+	}
+	t := c.Series
+	if c.Volume > 0 {
+		t += " Vol." + utils.IntToString(c.Volume)
+	}
+	if c.Number != "" {
+		t += " #" + c.Number
+	}
+	if c.Title != "" {
+		t += ": " + c.Title
+	}
+	return t
+}
+
+// Date publication date, as precise as possible: YYYY, YYYY-MM or YYYY-MM-DD
+func (c ComicInfo) Date() string {
+	if c.Year <= 0 {
+		return ""
+	}
+	if c.Month < 1 || c.Month > 12 {
+		return fmt.Sprintf("%04d", c.Year)
+	}
+	if c.Day < 1 || c.Day > 31 {
+		return fmt.Sprintf("%04d-%02d", c.Year, c.Month)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", c.Year, c.Month, c.Day)
+}
+
+// Writers list of writers
+func (c ComicInfo) Writers() []string {
+	return split(c.Writer)
+}
+
+// Contributors all persons with their role
+func (c ComicInfo) Contributors() []Contributor {
+	var contributors []Contributor
+	for _, r := range []struct {
+		Names string
+		Role  string
+	}{
+		{c.Writer, "aut"},
+		{c.Penciller, "art"},
+		{c.Inker, "art"},
+		{c.Colorist, "clr"},
+		{c.Letterer, "ill"},
+		{c.CoverArtist, "cov"},
+		{c.Editor, "edt"},
+	} {
+		for _, name := range split(r.Names) {
+			contributors = append(contributors, Contributor{name, r.Role})
+		}
+	}
+	return contributors
+}
+
+// Genres list of genres
+func (c ComicInfo) Genres() []string {
+	return split(c.Genre)
+}
+
+// multiple values are separated by a comma
+func split(s string) []string {
+	var r []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+	return r
+}
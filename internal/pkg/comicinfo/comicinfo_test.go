@@ -0,0 +1,143 @@
+package comicinfo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <Title>The Title</Title>
+  <Series>The Series</Series>
+  <Number>12</Number>
+  <Volume>2</Volume>
+  <Year>2021</Year>
+  <Month>3</Month>
+  <Writer>Alice, Bob</Writer>
+  <Penciller>Carol</Penciller>
+  <CoverArtist> Dave ,</CoverArtist>
+  <Genre>Action,Comedy</Genre>
+  <Manga>YesAndRightToLeft</Manga>
+  <Unknown>ignored</Unknown>
+  <Pages>
+    <Page Image="0" Type="FrontCover" ImageWidth="800" ImageHeight="1200" />
+    <Page Image="1" Type="Story Deleted" DoublePage="True" />
+  </Pages>
+</ComicInfo>`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.FullTitle(), "The Series Vol.2 #12: The Title"; got != want {
+		t.Errorf("full title: got %q, want %q", got, want)
+	}
+	if got, want := c.Date(), "2021-03"; got != want {
+		t.Errorf("date: got %q, want %q", got, want)
+	}
+	if !c.IsRightToLeft() {
+		t.Error("right to left: got false")
+	}
+	if got, want := c.Writers(), []string{"Alice", "Bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("writers: got %v, want %v", got, want)
+	}
+	if got, want := c.Genres(), []string{"Action", "Comedy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("genres: got %v, want %v", got, want)
+	}
+	wantContributors := []Contributor{{"Alice", "aut"}, {"Bob", "aut"}, {"Carol", "art"}, {"Dave", "cov"}}
+	if got := c.Contributors(); !reflect.DeepEqual(got, wantContributors) {
+		t.Errorf("contributors: got %v, want %v", got, wantContributors)
+	}
+	wantPages := []Page{
+		{Image: 0, Type: "FrontCover", ImageWidth: 800, ImageHeight: 1200},
+		{Image: 1, Type: "Story Deleted", DoublePage: true},
+	}
+	if !reflect.DeepEqual(c.Pages, wantPages) {
+		t.Errorf("pages: got %+v, want %+v", c.Pages, wantPages)
+	}
+	if !c.Pages[1].HasType("deleted") || c.Pages[1].HasType("FrontCover") {
+		t.Errorf("page types: %q", c.Pages[1].Type)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"", "<ComicInfo><Title>", "<ComicInfo><Volume>two</Volume></ComicInfo>"} {
+		if _, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+// the metadata written back in the epub can be read again
+func TestXML(t *testing.T) {
+	c, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Parse(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, c) {
+		t.Errorf("got %+v, want %+v", r, c)
+	}
+	if !(ComicInfo{}).IsEmpty() || c.IsEmpty() {
+		t.Error("is empty")
+	}
+}
+
+func TestFullTitle(t *testing.T) {
+	for _, tc := range []struct {
+		c    ComicInfo
+		want string
+	}{
+		{ComicInfo{}, ""},
+		{ComicInfo{Title: "Title", Volume: 2, Number: "3"}, "Title"},
+		{ComicInfo{Series: "Series"}, "Series"},
+		{ComicInfo{Series: "Series", Number: "1.5"}, "Series #1.5"},
+		{ComicInfo{Series: "Series", Volume: 3, Title: "Title"}, "Series Vol.3: Title"},
+	} {
+		if got := tc.c.FullTitle(); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	for _, tc := range []struct {
+		c    ComicInfo
+		want string
+	}{
+		{ComicInfo{}, ""},
+		{ComicInfo{Month: 3, Day: 4}, ""},
+		{ComicInfo{Year: 999}, "0999"},
+		{ComicInfo{Year: 2021, Month: 13, Day: 4}, "2021"},
+		{ComicInfo{Year: 2021, Month: 3, Day: 32}, "2021-03"},
+		{ComicInfo{Year: 2021, Month: 3, Day: 4}, "2021-03-04"},
+	} {
+		if got := tc.c.Date(); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestIsComicInfo(t *testing.T) {
+	for name, want := range map[string]bool{
+		"ComicInfo.xml":        true,
+		"comicinfo.XML":        true,
+		"book/ComicInfo.xml":   true,
+		`book\ComicInfo.xml`:   true,
+		"MyComicInfo.xml":      false,
+		"ComicInfo.xml/01.jpg": false,
+	} {
+		if got := IsComicInfo(name); got != want {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// extensions of the comics found when scanning a directory
+var comicExtensions = []string{
+	".cbz", ".zip",
+	".cbr", ".rar",
+	".cb7", ".7z",
+	".cbt", ".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst",
+	".pdf", ".epub",
+}
+
+// IsComic check the extension of the file
+func (c *Converter) IsComic(path string) bool {
+	lpath := strings.ToLower(path)
+	for _, ext := range comicExtensions {
+		if strings.HasSuffix(lpath, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Inputs list the comics to convert.
+//
+// the input and the extra arguments are used, glob patterns are expanded.
+// in batch mode, the directories are scanned for comics, each one is a book.
+func (c *Converter) Inputs() ([]string, error) {
+	patterns := c.args
+	if c.Options.Input != "" {
+		patterns = append([]string{c.Options.Input}, patterns...)
+	}
+	if len(patterns) == 0 {
+		return nil, errors.New("missing input")
+	}
+
+	inputs := make([]string, 0)
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if _, err := os.Stat(pattern); err != nil && strings.ContainsAny(pattern, "*?[") {
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no comic matches %q", pattern)
+			}
+		}
+
+		for _, match := range matches {
+			fi, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !c.Options.Batch || !fi.IsDir() {
+				inputs = append(inputs, match)
+				continue
+			}
+			found, err := c.scan(match)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, found...)
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, errors.New("no comic found")
+	}
+
+	return inputs, nil
+}
+
+// scan a directory for comics, the hidden files and the output directory are ignored
+func (c *Converter) scan(dir string) ([]string, error) {
+	output := filepath.Clean(c.Options.Output)
+	found := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if c.Options.Output != "" && path != dir && filepath.Clean(path) == output {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if c.IsComic(path) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// ForInput converter of one book of the batch.
+//
+// the options and the command line are shared, the workers are the part of the book.
+func (c *Converter) ForInput(input string, workers int) *Converter {
+	o := *c.Options
+	o.Input = input
+	o.Workers = workers
+	return &Converter{
+		Options: &o,
+		Cmd:     c.Cmd,
+		order:   c.order,
+		startAt: time.Now(),
+	}
+}
+
+// BatchParallel number of books converted at the same time.
+//
+// by default, one book for every 4 workers, so each book keeps enough workers for its images.
+func (c *Converter) BatchParallel(books int) int {
+	if c.Options.Dry {
+		return 1
+	}
+	parallel := c.Options.Parallel
+	if parallel == 0 {
+		parallel = c.Options.Workers / 4
+	}
+	return max(1, min(books, parallel))
+}
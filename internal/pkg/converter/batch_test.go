@@ -0,0 +1,135 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a.cbz", "b.CBR", "notes.txt", ".hidden.cbz", ".done/c.cbz",
+		"sub/d.tar.gz", "out/e.cbz", "images/01.png", "empty/readme.txt",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		input  string
+		args   []string
+		batch  bool
+		output string
+		want   []string
+		err    string
+	}{
+		{name: "one comic", input: p("a.cbz"), want: []string{p("a.cbz")}},
+		{name: "directory of images", input: p("images"), want: []string{p("images")}},
+		{name: "extra arguments", input: p("a.cbz"), args: []string{p("b.CBR")}, want: []string{p("a.cbz"), p("b.CBR")}},
+		{name: "glob", input: p("[ab]*"), want: []string{p("a.cbz"), p("b.CBR")}},
+		{
+			name: "scanned directory", input: dir, batch: true, output: p("out"),
+			want: []string{p("a.cbz"), p("b.CBR"), p("sub/d.tar.gz")},
+		},
+		{
+			name: "scanned directory and comic", input: p("sub"), args: []string{p("a.cbz")}, batch: true,
+			want: []string{p("sub/d.tar.gz"), p("a.cbz")},
+		},
+		{name: "missing input", err: "missing input"},
+		{name: "glob without match", input: p("*.pdf"), err: "no comic matches"},
+		{name: "missing comic", input: p("z.cbz"), err: "no such file"},
+		{name: "no comic in the directory", input: p("empty"), batch: true, err: "no comic found"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			c.Options.Input, c.Options.Batch, c.Options.Output = tc.input, tc.batch, tc.output
+			c.args = tc.args
+			got, err := c.Inputs()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// the books of the batch don't share their options
+func TestForInput(t *testing.T) {
+	c := New()
+	c.Options.Input, c.Options.Workers, c.Options.Title = "dir", 8, "title"
+	book := c.ForInput("dir/a.cbz", 2)
+	book.Options.Title = "other"
+	if book.Options.Input != "dir/a.cbz" || book.Options.Workers != 2 {
+		t.Errorf("book: got %s with %d workers", book.Options.Input, book.Options.Workers)
+	}
+	if c.Options.Input != "dir" || c.Options.Workers != 8 || c.Options.Title != "title" {
+		t.Errorf("batch options changed: %s, %d workers, %s", c.Options.Input, c.Options.Workers, c.Options.Title)
+	}
+}
+
+// the sources can be mixed with the options, after "--" they are never options
+func TestParseArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		sources []string
+		title   string
+	}{
+		{name: "options only", args: []string{"-input", "a.cbz", "-title", "t"}, title: "t"},
+		{name: "mixed", args: []string{"a.cbz", "-title", "t", "b.cbz"}, sources: []string{"a.cbz", "b.cbz"}, title: "t"},
+		{name: "after --", args: []string{"-title", "t", "--", "a.cbz", "-weird-name.cbz", "-title"}, sources: []string{"a.cbz", "-weird-name.cbz", "-title"}, title: "t"},
+		{name: "after a source and --", args: []string{"a.cbz", "--", "-weird-name.cbz"}, sources: []string{"a.cbz", "-weird-name.cbz"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			c.InitParse()
+			c.parseArgs(tc.args)
+			if strings.Join(c.args, ",") != strings.Join(tc.sources, ",") {
+				t.Errorf("sources: got %q, want %q", c.args, tc.sources)
+			}
+			if c.Options.Title != tc.title {
+				t.Errorf("title: got %q, want %q", c.Options.Title, tc.title)
+			}
+		})
+	}
+}
+
+func TestBatchParallel(t *testing.T) {
+	for _, tc := range []struct {
+		workers, parallel, books int
+		dry                      bool
+		want                     int
+	}{
+		{workers: 4, books: 10, want: 1},
+		{workers: 16, books: 10, want: 4},
+		{workers: 16, books: 2, want: 2},
+		{workers: 4, parallel: 3, books: 10, want: 3},
+		{workers: 4, parallel: 3, books: 2, want: 2},
+		{workers: 16, parallel: 3, books: 10, dry: true, want: 1},
+	} {
+		c := New()
+		c.Options.Workers, c.Options.Parallel, c.Options.Dry = tc.workers, tc.parallel, tc.dry
+		if got := c.BatchParallel(tc.books); got != tc.want {
+			t.Errorf("%+v: got %d", tc, got)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
 )
 
@@ -26,6 +27,7 @@ type Converter struct {
 	Cmd     *flag.FlagSet
 
 	order           []order
+	args            []string
 	isZeroValueErrs []error
 	startAt         time.Time
 }
@@ -98,16 +100,27 @@ func (c *Converter) AddBoolParam(p *bool, name string, value bool, usage string)
 // InitParse Initialize the parser with all section and parameter.
 func (c *Converter) InitParse() {
 	c.AddSection("Output")
-	c.AddStringParam(&c.Options.Input, "input", "", "Source of comic to convert: directory, cbz, zip, cbr, rar, pdf")
-	c.AddStringParam(&c.Options.Output, "output", "", "Output of the EPUB (directory or EPUB): (default [INPUT].epub)")
+	c.AddStringParam(&c.Options.Input, "input", "", "Source of comic to convert: directory, cbz, zip, cbr, rar, cb7, 7z, cbt, tar, tar.gz, tar.zst, pdf, epub\nMore sources or glob patterns can follow the options\nThe pdf pages with text or vector art need the tool built with the mupdf tag")
+	c.AddStringParam(&c.Options.Output, "output", "", "Output of the EPUB (directory or EPUB): (default [INPUT].epub)\nMust be a directory with multiple sources\nThe extension follows the output format")
+	c.AddBoolParam(&c.Options.Batch, "batch", false, "Scan the source directories for comics, and convert each one as a separate EPUB")
+	c.AddIntParam(&c.Options.Parallel, "parallel", 0, "Number of books converted at the same time with multiple sources, sharing the workers\n0 = one book for every 4 workers\nThe progress bars are disabled when more than one")
 	c.AddStringParam(&c.Options.Author, "author", "GO Comic Converter", "Author of the EPUB")
 	c.AddStringParam(&c.Options.Title, "title", "", "Title of the EPUB")
+	c.AddStringParam(&c.Options.Password, "password", "", "Password of encrypted zip, cbz, rar, cbr, 7z, cb7")
+	c.AddStringParam(&c.Options.PasswordFile, "password-file", "", "File containing the password of encrypted archives, for batch use")
+
+	c.AddSection("Watch")
+	c.AddBoolParam(&c.Options.Watch, "watch", false, "Watch the input directory, and convert the new comics into the output directory")
+	c.AddIntParam(&c.Options.WatchInterval, "watch-interval", c.Options.WatchInterval, "Seconds between 2 scans of the watched directory, a comic is converted once it stops changing")
 
 	c.AddSection("Config")
 	c.AddStringParam(&c.Options.Profile, "profile", c.Options.Profile, "Profile to use: \n"+c.Options.AvailableProfiles())
+	c.AddStringParam(&c.Options.OutputFormat, "output-format", c.Options.OutputFormat, "Format of the output:\nepub = ebook\nkepub = ebook for the native reader of the kobo devices (.kepub.epub)\nazw3 = ebook for the kindle devices, to sideload with usb\npdf = one page per image, for large devices like the reMarkable\ncbz = comic archive of the processed images with a ComicInfo.xml\nimages = directory of the processed images with a manifest.json (.images)")
 	c.AddIntParam(&c.Options.Image.Quality, "quality", c.Options.Image.Quality, "Quality of the image")
 	c.AddBoolParam(&c.Options.Image.GrayScale, "grayscale", c.Options.Image.GrayScale, "Grayscale image. Ideal for eInk devices.")
 	c.AddIntParam(&c.Options.Image.GrayScaleMode, "grayscale-mode", c.Options.Image.GrayScaleMode, "Grayscale Mode\n0 = normal\n1 = average\n2 = luminance")
+	c.AddIntParam(&c.Options.Image.Dither, "dither", c.Options.Image.Dither, "Dithering to the levels of gray of the eInk devices, after the resize. The images are grayscale png.\n0 = none\n1 = floyd-steinberg (error diffusion)\n2 = ordered (bayer)")
+	c.AddIntParam(&c.Options.Image.DitherLevels, "dither-levels", c.Options.Image.DitherLevels, "Levels of gray of the dithering: 4 or 16")
 	c.AddBoolParam(&c.Options.Image.Crop.Enabled, "crop", c.Options.Image.Crop.Enabled, "Crop images")
 	c.AddIntParam(&c.Options.Image.Crop.Left, "crop-ratio-left", c.Options.Image.Crop.Left, "Crop ratio left: ratio of pixels allow to be non blank while cutting on the left.")
 	c.AddIntParam(&c.Options.Image.Crop.Up, "crop-ratio-up", c.Options.Image.Crop.Up, "Crop ratio up: ratio of pixels allow to be non blank while cutting on the top.")
@@ -115,15 +128,27 @@ func (c *Converter) InitParse() {
 	c.AddIntParam(&c.Options.Image.Crop.Bottom, "crop-ratio-bottom", c.Options.Image.Crop.Bottom, "Crop ratio bottom: ratio of pixels allow to be non blank while cutting on the bottom.")
 	c.AddIntParam(&c.Options.Image.Crop.Limit, "crop-limit", c.Options.Image.Crop.Limit, "Crop limit: maximum number of cropping in percentage allowed. 0 mean unlimited.")
 	c.AddBoolParam(&c.Options.Image.Crop.SkipIfLimitReached, "crop-skip-if-limit-reached", c.Options.Image.Crop.SkipIfLimitReached, "Crop skip if limit reached.")
+	c.AddIntParam(&c.Options.Image.Crop.Border, "crop-border", c.Options.Image.Crop.Border, "Color of the margins removed by the crop, also used to detect the blank images\n0 = white\n1 = black\n2 = auto, from the outer line of each side")
+	c.AddIntParam(&c.Options.Image.Crop.Threshold, "crop-threshold", c.Options.Image.Crop.Threshold, "Crop threshold: difference of luminance with pure white or black, between 0 and 255, allowed in the margins.")
+	c.AddBoolParam(&c.Options.Image.Crop.IgnoreArtifacts, "crop-ignore-artifacts", c.Options.Image.Crop.IgnoreArtifacts, "Crop the page numbers, the watermarks and the dust isolated near the edges with the margins.")
+	c.AddIntParam(&c.Options.Image.Crop.Uniform, "crop-uniform", c.Options.Image.Crop.Uniform, "Crop the pages with the same box, the margins of all the pages are analyzed first\n0 = each page\n1 = one box for the book\n2 = one box per chapter")
+	c.AddBoolParam(&c.Options.Image.Crop.UniformOddEven, "crop-uniform-odd-even", c.Options.Image.Crop.UniformOddEven, "Crop uniform with a separate box for the odd and the even pages.")
 	c.AddIntParam(&c.Options.Image.Brightness, "brightness", c.Options.Image.Brightness, "Brightness readjustment: between -100 and 100, > 0 lighter, < 0 darker")
 	c.AddIntParam(&c.Options.Image.Contrast, "contrast", c.Options.Image.Contrast, "Contrast readjustment: between -100 and 100, > 0 more contrast, < 0 less contrast")
+	c.AddFloatParam(&c.Options.Image.Gamma, "gamma", c.Options.Image.Gamma, "Gamma correction: > 1 darker, < 1 lighter\n 1 = no correction\n 0 = default of the profile (1.8 for the kindle devices)\n-1 = auto from the histogram")
+	c.AddIntParam(&c.Options.Image.BlackPoint, "black-point", c.Options.Image.BlackPoint, "Levels: luminance between 0 and 255 displayed as black")
+	c.AddIntParam(&c.Options.Image.WhitePoint, "white-point", c.Options.Image.WhitePoint, "Levels: luminance between 0 and 255 displayed as white")
+	c.AddIntParam(&c.Options.Image.Sharpen, "sharpen", c.Options.Image.Sharpen, "Sharpen the line art after the resize: strength between 0 (none) and 100")
+	c.AddIntParam(&c.Options.Image.Denoise, "denoise", c.Options.Image.Denoise, "Remove the specks, the jpeg noise and the paper texture before the crop: strength between 0 (none) and 3")
 	c.AddBoolParam(&c.Options.Image.AutoContrast, "autocontrast", c.Options.Image.AutoContrast, "Improve contrast automatically")
 	c.AddBoolParam(&c.Options.Image.AutoRotate, "autorotate", c.Options.Image.AutoRotate, "Auto Rotate page when width > height")
-	c.AddBoolParam(&c.Options.Image.AutoSplitDoublePage, "autosplitdoublepage", c.Options.Image.AutoSplitDoublePage, "Auto Split double page when width > height")
+	c.AddBoolParam(&c.Options.Image.AutoSplitDoublePage, "autosplitdoublepage", c.Options.Image.AutoSplitDoublePage, "Auto Split double page when width > height, at the gutter detected around the middle")
 	c.AddBoolParam(&c.Options.Image.KeepDoublePageIfSplit, "keepdoublepageifsplit", c.Options.Image.KeepDoublePageIfSplit, "Keep the double page if split")
 	c.AddBoolParam(&c.Options.Image.KeepSplitDoublePageAspect, "keepsplitdoublepageaspect", c.Options.Image.KeepSplitDoublePageAspect, "Keep aspect of split part of a double page (best for landscape rendering)")
 	c.AddBoolParam(&c.Options.Image.NoBlankImage, "noblankimage", c.Options.Image.NoBlankImage, "Remove blank image")
 	c.AddBoolParam(&c.Options.Image.Manga, "manga", c.Options.Image.Manga, "Manga mode (right to left)")
+	c.AddBoolParam(&c.Options.Image.Webtoon, "webtoon", c.Options.Image.Webtoon, "Webtoon mode: stitch the vertical strips of each directory, then slice them into pages of the size of the device.\nThe cuts land in the gutters between the panels when possible.")
+	c.AddIntParam(&c.Options.Image.WebtoonOverlap, "webtoon-overlap", c.Options.Image.WebtoonOverlap, "Webtoon overlap: percentage of the page repeated on the next one, when the cut goes through a panel. Between 0 and 50.")
 	c.AddBoolParam(&c.Options.Image.HasCover, "hascover", c.Options.Image.HasCover, "Has cover. Indicate if your comic have a cover. The first page will be used as a cover and include after the title.")
 	c.AddIntParam(&c.Options.LimitMb, "limitmb", c.Options.LimitMb, "Limit size of the EPUB: Default nolimit (0), Minimum 20")
 	c.AddBoolParam(&c.Options.StripFirstDirectoryFromToc, "strip", c.Options.StripFirstDirectoryFromToc, "Strip first directory from the TOC if only 1")
@@ -155,7 +180,7 @@ func (c *Converter) InitParse() {
 	c.AddSection("Other")
 	c.AddIntParam(&c.Options.Workers, "workers", runtime.NumCPU(), "Number of workers")
 	c.AddBoolParam(&c.Options.Dry, "dry", false, "Dry run to show all options")
-	c.AddBoolParam(&c.Options.DryVerbose, "dry-verbose", false, "Display also sorted files after the TOC")
+	c.AddBoolParam(&c.Options.DryVerbose, "dry-verbose", false, "Display also sorted files after the TOC, and the split of the double pages")
 	c.AddBoolParam(&c.Options.Quiet, "quiet", false, "Disable progress bar")
 	c.AddBoolParam(&c.Options.Json, "json", false, "Output progression and information in Json format")
 	c.AddBoolParam(&c.Options.Version, "version", false, "Show current and available version")
@@ -228,9 +253,7 @@ func (c *Converter) isZeroValue(f *flag.Flag, value string) (ok bool, err error)
 
 // Parse all parameters
 func (c *Converter) Parse() {
-	if err := c.Cmd.Parse(os.Args[1:]); err != nil {
-		utils.Fatalf("cannot parse command line options: %v", err)
-	}
+	c.parseArgs(os.Args[1:])
 	if c.Options.Help {
 		c.Cmd.Usage()
 		os.Exit(0)
@@ -271,6 +294,18 @@ func (c *Converter) Parse() {
 		c.Options.Image.AutoRotate = false
 		c.Options.Image.NoBlankImage = false
 		c.Options.Image.Resize = false
+		c.Options.Image.Dither = 0
+		c.Options.Image.Gamma = 1
+		c.Options.Image.BlackPoint = 0
+		c.Options.Image.WhitePoint = 255
+		c.Options.Image.Sharpen = 0
+		c.Options.Image.Denoise = 0
+	}
+
+	// the jpeg compression would blur the dithering
+	if c.Options.Image.Dither != 0 {
+		c.Options.Image.Format = "png"
+		c.Options.Image.GrayScale = true
 	}
 
 	if c.Options.Image.AppleBookCompatibility {
@@ -284,6 +319,27 @@ func (c *Converter) Parse() {
 	}
 }
 
+// parse the options, the extra sources can be mixed with them.
+//
+// after "--", all the arguments are sources, even when they look like options.
+func (c *Converter) parseArgs(args []string) {
+	for {
+		if err := c.Cmd.Parse(args); err != nil {
+			utils.Fatalf("cannot parse command line options: %v", err)
+		}
+		rest := c.Cmd.Args()
+		if len(rest) == 0 {
+			return
+		}
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			c.args = append(c.args, rest...)
+			return
+		}
+		c.args = append(c.args, rest[0])
+		args = rest[1:]
+	}
+}
+
 // Validate Check parameters
 func (c *Converter) Validate() error {
 	// Check input
@@ -296,14 +352,34 @@ func (c *Converter) Validate() error {
 		return err
 	}
 
+	if c.Options.Parallel < 0 {
+		return errors.New("parallel should be positive")
+	}
+
+	// Output format
+	if !(c.Options.OutputFormat == "epub" || c.Options.OutputFormat == "kepub" || c.Options.OutputFormat == "azw3" || c.Options.OutputFormat == "pdf" || c.Options.OutputFormat == "cbz" || c.Options.OutputFormat == "images") {
+		return errors.New("output format should be epub, kepub, azw3, pdf, cbz or images")
+	}
+	outputExt := c.Options.OutputExt()
+
 	// Check Output
-	var defaultOutput string
+	var defaultOutput, defaultTitle string
 	inputBase := filepath.Clean(c.Options.Input)
 	if fi.IsDir() {
-		defaultOutput = inputBase + ".epub"
+		defaultOutput = inputBase + outputExt
+		defaultTitle = filepath.Base(inputBase)
 	} else {
 		ext := filepath.Ext(inputBase)
-		defaultOutput = inputBase[0:len(inputBase)-len(ext)] + ".epub"
+		// compressed tarball: .tar.gz, .tar.zst, or kobo epub: .kepub.epub
+		if subExt := filepath.Ext(inputBase[0 : len(inputBase)-len(ext)]); strings.EqualFold(subExt, ".tar") || strings.EqualFold(subExt, ".kepub") {
+			ext = subExt + ext
+		}
+		defaultOutput = inputBase[0:len(inputBase)-len(ext)] + outputExt
+		defaultTitle = filepath.Base(inputBase[0 : len(inputBase)-len(ext)])
+		// the input has already the format of the output (epub for another profile, cbz), keep the original
+		if strings.EqualFold(ext, outputExt) {
+			defaultOutput = inputBase[0:len(inputBase)-len(ext)] + " (" + c.Options.Profile + ")" + outputExt
+		}
 	}
 
 	if c.Options.Output == "" {
@@ -311,7 +387,7 @@ func (c *Converter) Validate() error {
 	}
 
 	c.Options.Output = filepath.Clean(c.Options.Output)
-	if filepath.Ext(c.Options.Output) == ".epub" {
+	if strings.HasSuffix(c.Options.Output, outputExt) {
 		fo, err := os.Stat(filepath.Dir(c.Options.Output))
 		if err != nil {
 			return err
@@ -325,7 +401,7 @@ func (c *Converter) Validate() error {
 			return err
 		}
 		if !fo.IsDir() {
-			return errors.New("output must be an existing dir or end with .epub")
+			return errors.New("output must be an existing dir or end with " + outputExt)
 		}
 		c.Options.Output = filepath.Join(
 			c.Options.Output,
@@ -333,10 +409,29 @@ func (c *Converter) Validate() error {
 		)
 	}
 
+	if c.Options.Output == inputBase {
+		return errors.New("output can't be the input")
+	}
+
+	// Password
+	if c.Options.PasswordFile != "" {
+		if c.Options.Password != "" {
+			return errors.New("password and password-file can't be used together")
+		}
+		b, err := os.ReadFile(c.Options.PasswordFile)
+		if err != nil {
+			return err
+		}
+		c.Options.Password, _, _ = strings.Cut(string(b), "\n")
+		c.Options.Password = strings.TrimSuffix(c.Options.Password, "\r")
+		if c.Options.Password == "" {
+			return errors.New("password-file is empty")
+		}
+	}
+
 	// Title
 	if c.Options.Title == "" {
-		ext := filepath.Ext(defaultOutput)
-		c.Options.Title = filepath.Base(defaultOutput[0 : len(defaultOutput)-len(ext)])
+		c.Options.Title = defaultTitle
 	}
 
 	// Profile
@@ -363,6 +458,33 @@ func (c *Converter) Validate() error {
 		return errors.New("contrast should be between -100 and 100")
 	}
 
+	// Gamma
+	if c.Options.Image.Gamma < 0 && c.Options.Image.Gamma != -1 {
+		return errors.New("gamma should be -1, 0 or > 0")
+	}
+	// the default of the profile, no correction without profile. The auto gamma is only set explicitly.
+	if c.Options.Image.Gamma == 0 {
+		c.Options.Image.Gamma = 1
+		if profile := c.Options.GetProfile(); profile != nil && profile.Gamma > 0 {
+			c.Options.Image.Gamma = profile.Gamma
+		}
+	}
+
+	// Levels
+	if c.Options.Image.BlackPoint < 0 || c.Options.Image.WhitePoint > 255 || c.Options.Image.BlackPoint >= c.Options.Image.WhitePoint {
+		return errors.New("black and white points should be between 0 and 255, black below white")
+	}
+
+	// Sharpen
+	if c.Options.Image.Sharpen < 0 || c.Options.Image.Sharpen > 100 {
+		return errors.New("sharpen should be between 0 and 100")
+	}
+
+	// Denoise
+	if c.Options.Image.Denoise < 0 || c.Options.Image.Denoise > 3 {
+		return errors.New("denoise should be between 0 and 3")
+	}
+
 	// SortPathMode
 	if c.Options.SortPathMode < 0 || c.Options.SortPathMode > 2 {
 		return errors.New("sort should be 0, 1 or 2")
@@ -398,14 +520,58 @@ func (c *Converter) Validate() error {
 		return errors.New("grayscale mode should be 0, 1 or 2")
 	}
 
+	// Dither
+	if c.Options.Image.Dither < 0 || c.Options.Image.Dither > 2 {
+		return errors.New("dither should be 0, 1 or 2")
+	}
+	if c.Options.Image.DitherLevels != 4 && c.Options.Image.DitherLevels != 16 {
+		return errors.New("dither levels should be 4 or 16")
+	}
+
 	// crop
 	if c.Options.Image.Crop.Limit < 0 || c.Options.Image.Crop.Limit > 100 {
 		return errors.New("crop limit should be between 0 and 100")
 	}
+	if c.Options.Image.Crop.Border < 0 || c.Options.Image.Crop.Border > 2 {
+		return errors.New("crop border should be 0, 1 or 2")
+	}
+	if c.Options.Image.Crop.Threshold < 0 || c.Options.Image.Crop.Threshold > 255 {
+		return errors.New("crop threshold should be between 0 and 255")
+	}
+	if c.Options.Image.Crop.Uniform < 0 || c.Options.Image.Crop.Uniform > 2 {
+		return errors.New("crop uniform should be 0, 1 or 2")
+	}
+
+	// webtoon
+	if c.Options.Image.WebtoonOverlap < 0 || c.Options.Image.WebtoonOverlap > 50 {
+		return errors.New("webtoon overlap should be between 0 and 50")
+	}
 
 	return nil
 }
 
+// ApplyComicInfo Use the metadata of the ComicInfo.xml, explicit flags take precedence.
+func (c *Converter) ApplyComicInfo(info comicinfo.ComicInfo) {
+	c.Options.ComicInfo = info
+
+	isSet := map[string]bool{}
+	c.Cmd.Visit(func(f *flag.Flag) {
+		isSet[f.Name] = true
+	})
+
+	if title := info.FullTitle(); title != "" && !isSet["title"] {
+		c.Options.Title = title
+	}
+
+	if writers := info.Writers(); len(writers) > 0 && !isSet["author"] {
+		c.Options.Author = strings.Join(writers, ", ")
+	}
+
+	if info.IsRightToLeft() && !isSet["manga"] {
+		c.Options.Image.Manga = true
+	}
+}
+
 // Fatal Helper to show usage, err and exit 1
 func (c *Converter) Fatal(err error) {
 	c.Cmd.Usage()
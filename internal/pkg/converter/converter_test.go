@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateGamma(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "book.cbz")
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		profile string
+		gamma   float64
+		want    float64
+		err     string
+	}{
+		{"no correction by default", "KV", NewOptions().Image.Gamma, 1, ""},
+		{"default of the profile", "KV", 0, 1.8, ""},
+		{"profile without correction", "SR", 0, 1, ""},
+		{"explicit", "KV", 1.2, 1.2, ""},
+		{"auto", "KV", -1, -1, ""},
+		{"invalid", "KV", -2, 0, "gamma should be"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			c.Options.Input, c.Options.Profile, c.Options.Image.Gamma = input, tc.profile, tc.gamma
+			err := c.Validate()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Options.Image.Gamma != tc.want {
+				t.Errorf("got %v, want %v", c.Options.Image.Gamma, tc.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,15 @@ import (
 type Options struct {
 	epuboptions.EPUBOptions
 
+	// Output
+	PasswordFile string `yaml:"-" json:"-"`
+	Batch        bool   `yaml:"-" json:"-"`
+	Parallel     int    `yaml:"-" json:"-"`
+
+	// Watch
+	Watch         bool `yaml:"-" json:"-"`
+	WatchInterval int  `yaml:"-" json:"-"`
+
 	// Config
 	Profile string `yaml:"profile" json:"profile"`
 
@@ -49,11 +58,12 @@ func NewOptions() *Options {
 				Quality:   85,
 				GrayScale: true,
 				Crop: epuboptions.Crop{
-					Enabled: true,
-					Left:    1,
-					Up:      1,
-					Right:   1,
-					Bottom:  3,
+					Enabled:   true,
+					Left:      1,
+					Up:        1,
+					Right:     1,
+					Bottom:    3,
+					Threshold: 31,
 				},
 				NoBlankImage:              true,
 				HasCover:                  true,
@@ -65,13 +75,18 @@ func NewOptions() *Options {
 						Background: "FFF",
 					},
 				},
-				Resize: true,
-				Format: "jpeg",
+				Gamma:        1,
+				WhitePoint:   255,
+				Resize:       true,
+				Format:       "jpeg",
+				DitherLevels: 16,
 			},
+			OutputFormat: "epub",
 			TitlePage:    1,
 			SortPathMode: 1,
 		},
-		profiles: NewProfiles(),
+		WatchInterval: 10,
+		profiles:      NewProfiles(),
 	}
 }
 
@@ -169,6 +184,44 @@ func (o *Options) ShowConfig() string {
 		grayscaleMode = "luminance"
 	}
 
+	gamma := utils.FloatToString(o.Image.Gamma, 2)
+	switch o.Image.Gamma {
+	case 0:
+		gamma = "profile"
+		if profile != nil {
+			gamma += " (" + utils.FloatToString(profile.Gamma, 2) + ")"
+		}
+	case -1:
+		gamma = "auto"
+	}
+
+	cropBorder := "white"
+	switch o.Image.Crop.Border {
+	case 1:
+		cropBorder = "black"
+	case 2:
+		cropBorder = "auto"
+	}
+
+	cropUniform := "each page"
+	switch o.Image.Crop.Uniform {
+	case 1:
+		cropUniform = "book"
+	case 2:
+		cropUniform = "chapter"
+	}
+	if o.Image.Crop.Uniform != 0 && o.Image.Crop.UniformOddEven {
+		cropUniform += " - odd and even pages"
+	}
+
+	dither := "none"
+	switch o.Image.Dither {
+	case 1:
+		dither = "floyd-steinberg"
+	case 2:
+		dither = "ordered"
+	}
+
 	var b strings.Builder
 	for _, v := range []struct {
 		Key       string
@@ -176,10 +229,13 @@ func (o *Options) ShowConfig() string {
 		Condition bool
 	}{
 		{"Profile", profileDesc, true},
+		{"Output format", o.OutputFormat, true},
 		{"Format", o.Image.Format, true},
 		{"Quality", o.Image.Quality, o.Image.Format == "jpeg"},
 		{"Grayscale", o.Image.GrayScale, true},
 		{"Grayscale mode", grayscaleMode, o.Image.GrayScale},
+		{"Dither", dither, true},
+		{"Dither levels", o.Image.DitherLevels, o.Image.Dither != 0},
 		{"Crop", o.Image.Crop.Enabled, true},
 		{"Crop ratio",
 			utils.IntToString(o.Image.Crop.Left) + " Left - " +
@@ -189,15 +245,24 @@ func (o *Options) ShowConfig() string {
 				"Limit " + utils.IntToString(o.Image.Crop.Limit) + "% - " +
 				"Skip " + utils.BoolToString(o.Image.Crop.SkipIfLimitReached),
 			o.Image.Crop.Enabled},
+		{"Crop border", cropBorder + " - Threshold " + utils.IntToString(o.Image.Crop.Threshold), o.Image.Crop.Enabled},
+		{"Crop ignore artifacts", o.Image.Crop.IgnoreArtifacts, o.Image.Crop.Enabled},
+		{"Crop uniform", cropUniform, o.Image.Crop.Enabled},
 		{"Brightness", o.Image.Brightness, o.Image.Brightness != 0},
 		{"Contrast", o.Image.Contrast, o.Image.Contrast != 0},
 		{"Auto contrast", o.Image.AutoContrast, true},
+		{"Gamma", gamma, true},
+		{"Levels", utils.IntToString(o.Image.BlackPoint) + " Black - " + utils.IntToString(o.Image.WhitePoint) + " White", o.Image.BlackPoint != 0 || o.Image.WhitePoint != 255},
+		{"Sharpen", o.Image.Sharpen, o.Image.Sharpen != 0},
+		{"Denoise", o.Image.Denoise, o.Image.Denoise != 0},
 		{"Auto rotate", o.Image.AutoRotate, true},
 		{"Auto split double page", o.Image.AutoSplitDoublePage, o.Image.View.PortraitOnly || !o.Image.AppleBookCompatibility},
 		{"Keep double page if split", o.Image.KeepDoublePageIfSplit, (o.Image.View.PortraitOnly || !o.Image.AppleBookCompatibility) && o.Image.AutoSplitDoublePage},
 		{"Keep split double page aspect", o.Image.KeepSplitDoublePageAspect, (o.Image.View.PortraitOnly || !o.Image.AppleBookCompatibility) && o.Image.AutoSplitDoublePage},
 		{"No blank image", o.Image.NoBlankImage, true},
 		{"Manga", o.Image.Manga, true},
+		{"Webtoon", o.Image.Webtoon, true},
+		{"Webtoon overlap", utils.IntToString(o.Image.WebtoonOverlap) + "%", o.Image.Webtoon},
 		{"Has cover", o.Image.HasCover, true},
 		{"Limit", utils.IntToString(o.LimitMb) + " Mb", o.LimitMb != 0},
 		{"Strip first directory from toc", o.StripFirstDirectoryFromToc, true},
@@ -207,7 +272,7 @@ func (o *Options) ShowConfig() string {
 		{"Resize", o.Image.Resize, true},
 		{"Aspect ratio", aspectRatio, true},
 		{"Portrait only", o.Image.View.PortraitOnly, true},
-		{"Title page", titlePage, true},
+		{"Title page", titlePage, o.OutputFormat != "cbz" && o.OutputFormat != "images"},
 		{"Apple book compatibility", o.Image.AppleBookCompatibility, !o.Image.View.PortraitOnly},
 	} {
 		if v.Condition {
@@ -9,10 +9,11 @@ import (
 )
 
 type Profile struct {
-	Code        string `json:"code"`
-	Description string `json:"description"`
-	Width       int    `json:"width"`
-	Height      int    `json:"height"`
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Gamma       float64 `json:"gamma"`
 }
 
 func (p Profile) String() string {
@@ -26,37 +27,37 @@ func NewProfiles() Profiles {
 	res := make(Profiles)
 	for _, r := range []Profile{
 		// High Resolution for Tablet
-		{"HR", "High Resolution", 2400, 3840},
-		{"SR", "Standard Resolution", 1200, 1920},
+		{"HR", "High Resolution", 2400, 3840, 1},
+		{"SR", "Standard Resolution", 1200, 1920, 1},
 		//Kindle
-		{"K1", "Kindle 1", 600, 670},
-		{"K11", "Kindle 11", 1072, 1448},
-		{"K2", "Kindle 2", 600, 670},
-		{"K34", "Kindle Keyboard/Touch", 600, 800},
-		{"K578", "Kindle", 600, 800},
-		{"KDX", "Kindle DX/DXG", 824, 1000},
-		{"KPW", "Kindle Paperwhite 1/2", 758, 1024},
-		{"KV", "Kindle Paperwhite 3/4/Voyage/Oasis", 1072, 1448},
-		{"KPW5", "Kindle Paperwhite 5/Signature Edition", 1236, 1648},
-		{"KO", "Kindle Oasis 2/3", 1264, 1680},
-		{"KS", "Kindle Scribe", 1860, 2480},
+		{"K1", "Kindle 1", 600, 670, 1.8},
+		{"K11", "Kindle 11", 1072, 1448, 1.8},
+		{"K2", "Kindle 2", 600, 670, 1.8},
+		{"K34", "Kindle Keyboard/Touch", 600, 800, 1.8},
+		{"K578", "Kindle", 600, 800, 1.8},
+		{"KDX", "Kindle DX/DXG", 824, 1000, 1.8},
+		{"KPW", "Kindle Paperwhite 1/2", 758, 1024, 1.8},
+		{"KV", "Kindle Paperwhite 3/4/Voyage/Oasis", 1072, 1448, 1.8},
+		{"KPW5", "Kindle Paperwhite 5/Signature Edition", 1236, 1648, 1.8},
+		{"KO", "Kindle Oasis 2/3", 1264, 1680, 1.8},
+		{"KS", "Kindle Scribe", 1860, 2480, 1.8},
 		// Kobo
-		{"KoMT", "Kobo Mini/Touch", 600, 800},
-		{"KoG", "Kobo Glo", 768, 1024},
-		{"KoGHD", "Kobo Glo HD", 1072, 1448},
-		{"KoA", "Kobo Aura", 758, 1024},
-		{"KoAHD", "Kobo Aura HD", 1080, 1440},
-		{"KoAH2O", "Kobo Aura H2O", 1080, 1430},
-		{"KoAO", "Kobo Aura ONE", 1404, 1872},
-		{"KoN", "Kobo Nia", 758, 1024},
-		{"KoC", "Kobo Clara HD/Kobo Clara 2E", 1072, 1448},
-		{"KoL", "Kobo Libra H2O/Kobo Libra 2", 1264, 1680},
-		{"KoF", "Kobo Forma", 1440, 1920},
-		{"KoS", "Kobo Sage", 1440, 1920},
-		{"KoE", "Kobo Elipsa", 1404, 1872},
+		{"KoMT", "Kobo Mini/Touch", 600, 800, 1},
+		{"KoG", "Kobo Glo", 768, 1024, 1},
+		{"KoGHD", "Kobo Glo HD", 1072, 1448, 1},
+		{"KoA", "Kobo Aura", 758, 1024, 1},
+		{"KoAHD", "Kobo Aura HD", 1080, 1440, 1},
+		{"KoAH2O", "Kobo Aura H2O", 1080, 1430, 1},
+		{"KoAO", "Kobo Aura ONE", 1404, 1872, 1},
+		{"KoN", "Kobo Nia", 758, 1024, 1},
+		{"KoC", "Kobo Clara HD/Kobo Clara 2E", 1072, 1448, 1},
+		{"KoL", "Kobo Libra H2O/Kobo Libra 2", 1264, 1680, 1},
+		{"KoF", "Kobo Forma", 1440, 1920, 1},
+		{"KoS", "Kobo Sage", 1440, 1920, 1},
+		{"KoE", "Kobo Elipsa", 1404, 1872, 1},
 		// reMarkable
-		{"RM1", "reMarkable 1", 1404, 1872},
-		{"RM2", "reMarkable 2", 1404, 1872},
+		{"RM1", "reMarkable 1", 1404, 1872, 1},
+		{"RM2", "reMarkable 2", 1404, 1872, 1},
 	} {
 		res[r.Code] = r
 	}
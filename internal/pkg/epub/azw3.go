@@ -0,0 +1,180 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/azw3"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimageprocessor"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubtemplates"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+// read the image of the storage
+func (e EPUB) readImage(fz *zip.File) ([]byte, error) {
+	r, err := fz.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(r io.ReadCloser) {
+		_ = r.Close()
+	}(r)
+	return io.ReadAll(r)
+}
+
+// chapters of the table of content, the directories are flattened
+func (e EPUB) azw3Chapters(images []epubimage.EPUBImage, firstPage int) []azw3.Chapter {
+	firstDirs := map[string]bool{}
+	for _, img := range images {
+		firstDirs[strings.Split(img.Path, string(filepath.Separator))[0]] = true
+	}
+	strip := e.StripFirstDirectoryFromToc && len(firstDirs) == 1
+
+	var (
+		chapters  []azw3.Chapter
+		lastPath  = "."
+		bookmarks = map[int]bool{}
+	)
+	for i, img := range images {
+		if path := filepath.Clean(img.Path); path != lastPath {
+			lastPath = path
+			dirs := strings.Split(path, string(filepath.Separator))
+			if strip {
+				dirs = dirs[1:]
+			}
+			if len(dirs) > 0 && path != "." {
+				chapters = append(chapters, azw3.Chapter{Title: strings.Join(dirs, " / "), Page: firstPage + i})
+			}
+		}
+		if img.Bookmark != "" && !bookmarks[img.Id] {
+			bookmarks[img.Id] = true
+			chapters = append(chapters, azw3.Chapter{Title: img.Bookmark, Page: firstPage + i})
+		}
+	}
+	return chapters
+}
+
+// write a part as an AZW3 (KF8) for the kindle devices.
+//
+// the pages and the metadata are the ones of the EPUB, the blank pages are not needed.
+func (e EPUB) writeAZW3Part(path string, currentPart, totalParts int, part epubPart, imgStorage epubzip.StorageImageReader) error {
+	hasTitlePage := e.TitlePage == 1 || (e.TitlePage == 2 && totalParts > 1)
+
+	title := e.Title
+	if totalParts > 1 {
+		title = title + " [" + utils.IntToString(currentPart) + "/" + utils.IntToString(totalParts) + "]"
+	}
+
+	content := epubtemplates.Content{
+		Title:        title,
+		HasTitlePage: hasTitlePage,
+		UID:          e.UID,
+		Author:       e.Author,
+		Publisher:    e.Publisher,
+		UpdatedAt:    e.UpdatedAt,
+		ComicInfo:    e.ComicInfo,
+		ImageOptions: e.Image,
+		Cover:        part.Cover,
+		Images:       part.Images,
+		Current:      currentPart,
+		Total:        totalParts,
+	}
+
+	book := azw3.Book{
+		UID:         e.UID,
+		Title:       title,
+		RightToLeft: e.Image.Manga,
+		CSS: e.render(epubtemplates.Style, map[string]any{
+			"View": e.Image.View,
+		}),
+		CoverId: 0,
+	}
+	for _, m := range content.Metadata() {
+		book.Meta = append(book.Meta, azw3.Meta{Name: m.Name, Value: m.Value})
+	}
+
+	// page referencing the image with the kindle urls
+	addPage := func(pageTitle string, imgPath string, data []byte, style string) {
+		id := len(book.Resources)
+		book.Resources = append(book.Resources, azw3.Resource{Data: data, Mime: e.Image.MediaType()})
+		page := e.render(epubtemplates.Text, map[string]any{
+			"Title":      pageTitle,
+			"ViewPort":   e.Image.View.Port(),
+			"ImagePath":  imgPath,
+			"ImageStyle": style,
+		})
+		page = strings.Replace(page, `href="style.css"`, `href="`+azw3.FlowURL()+`"`, 1)
+		page = strings.Replace(page, `src="../`+imgPath+`"`, `src="`+azw3.EmbedURL(id, e.Image.MediaType())+`"`, 1)
+		book.Pages = append(book.Pages, page)
+	}
+
+	text := ""
+	if totalParts > 1 {
+		text = utils.IntToString(currentPart) + " / " + utils.IntToString(totalParts)
+	}
+	cover, err := e.imageProcessor.CoverTitleData(epubimageprocessor.CoverTitleDataOptions{
+		Src:         part.Cover.Raw,
+		Name:        "cover",
+		Text:        text,
+		Align:       "bottom",
+		PctWidth:    50,
+		PctMargin:   50,
+		MaxFontSize: 96,
+		BorderSize:  8,
+	})
+	if err != nil {
+		return err
+	}
+	coverData, err := io.ReadAll(flate.NewReader(bytes.NewReader(cover.Data)))
+	if err != nil {
+		return err
+	}
+	// the cover is only a resource, the kindle displays it from the metadata
+	book.Resources = append(book.Resources, azw3.Resource{Data: coverData, Mime: e.Image.MediaType()})
+
+	if hasTitlePage {
+		titleImage, err := e.imageProcessor.CoverTitleData(epubimageprocessor.CoverTitleDataOptions{
+			Src:         part.Cover.Raw,
+			Name:        "title",
+			Text:        title,
+			Align:       "center",
+			PctWidth:    100,
+			PctMargin:   100,
+			MaxFontSize: 64,
+			BorderSize:  4,
+		})
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(flate.NewReader(bytes.NewReader(titleImage.Data)))
+		if err != nil {
+			return err
+		}
+		addPage(title, "Images/title."+e.Image.Format, data, part.Cover.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""))
+	}
+
+	book.Chapters = e.azw3Chapters(part.Images, len(book.Pages))
+	if len(book.Chapters) == 0 {
+		book.Chapters = []azw3.Chapter{{Title: title, Page: 0}}
+	}
+	for _, img := range part.Images {
+		data, err := e.readImage(imgStorage.Get(img.EPUBImgPath()))
+		if err != nil {
+			return err
+		}
+		addPage(
+			"Image "+utils.IntToString(img.Id)+" Part "+utils.IntToString(img.Part),
+			img.ImgPath(),
+			data,
+			img.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""),
+		)
+	}
+
+	return book.WriteFile(path)
+}
@@ -0,0 +1,82 @@
+package epub
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+// comicInfo of a part, the pages are described in the order of the archive
+func (e EPUB) comicInfo(currentPart, totalParts int, pages []epubimage.EPUBImage) comicinfo.ComicInfo {
+	info := e.ComicInfo
+	// the title is replaced only if it doesn't come from the ComicInfo.xml
+	if e.Title != info.FullTitle() {
+		info.Title, info.Series, info.Volume, info.Number = e.Title, "", 0, ""
+	}
+	if totalParts > 1 {
+		info.Title += " [" + utils.IntToString(currentPart) + "/" + utils.IntToString(totalParts) + "]"
+	}
+	if info.Writer == "" {
+		info.Writer = e.Author
+	}
+	if e.Image.Manga {
+		info.Manga = "YesAndRightToLeft"
+	}
+	info.PageCount = len(pages)
+	info.Pages = make([]comicinfo.Page, 0, len(pages))
+
+	lastPath := ""
+	for i, img := range pages {
+		page := comicinfo.Page{
+			Image:       i,
+			DoublePage:  img.DoublePage,
+			ImageWidth:  img.Width,
+			ImageHeight: img.Height,
+			Bookmark:    img.Bookmark,
+		}
+		if i == 0 && e.Image.HasCover {
+			page.Type = "FrontCover"
+		} else {
+			// a new directory start a chapter
+			if page.Bookmark == "" && img.Path != lastPath && img.Path != "" && img.Path != "." {
+				page.Bookmark = filepath.Base(img.Path)
+			}
+			lastPath = img.Path
+		}
+		info.Pages = append(info.Pages, page)
+	}
+	return info
+}
+
+// write a part as a CBZ, the processed images are copied in the reading order with a ComicInfo.xml
+func (e EPUB) writeCBZPart(path string, currentPart, totalParts int, part epubPart, imgStorage epubzip.StorageImageReader) error {
+	pages := part.Images
+	if e.Image.HasCover {
+		pages = append([]epubimage.EPUBImage{part.Cover}, pages...)
+	}
+
+	wz, err := epubzip.New(path)
+	if err != nil {
+		return err
+	}
+	defer func(wz epubzip.EPUBZip) {
+		_ = wz.Close()
+	}(wz)
+
+	fmtPage := utils.FormatNumberOfDigits(len(pages))
+	for i, img := range pages {
+		if err = wz.CopyAs(imgStorage.Get(img.EPUBImgPath()), fmt.Sprintf(fmtPage, i+1)+"."+img.Format); err != nil {
+			return err
+		}
+	}
+
+	info, err := e.comicInfo(currentPart, totalParts, pages).XML()
+	if err != nil {
+		return err
+	}
+	return wz.WriteContent("ComicInfo.xml", info)
+}
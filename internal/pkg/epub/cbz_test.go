@@ -0,0 +1,95 @@
+package epub
+
+import (
+	"archive/zip"
+	"image"
+	_ "image/jpeg"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
+)
+
+func TestWriteCBZPart(t *testing.T) {
+	// the cover is marked on the third image by the ComicInfo.xml
+	bookmarked := testImage(3, "Chapter 1")
+	bookmarked.Bookmark = "Fight"
+	e, part, storage := testBook(t, true,
+		testImage(2, "Chapter 1"),
+		testImage(0, ""),
+		testImage(1, "Chapter 1"),
+		bookmarked,
+		testImage(4, "Chapter 2"),
+	)
+	path := e.Output + ".cbz"
+	if err := e.writeCBZPart(path, 1, 2, part, storage); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	// the pages in the reading order, the cover first, then the ComicInfo.xml
+	wantIds := []int{2, 0, 1, 3, 4}
+	if len(r.File) != len(wantIds)+1 {
+		t.Fatalf("got %d files", len(r.File))
+	}
+	for i, id := range wantIds {
+		f := r.File[i]
+		if want := string(rune('1'+i)) + ".jpeg"; f.Name != want {
+			t.Errorf("page %d: got %s, want %s", i, f.Name, want)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, _, err := image.Decode(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		checkGray(t, f.Name, img, id)
+	}
+
+	f := r.File[len(wantIds)]
+	if f.Name != "ComicInfo.xml" {
+		t.Fatalf("got %s, want ComicInfo.xml", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	info, err := comicinfo.Parse(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Book [1/2]" || info.Writer != "Author" || info.PageCount != len(wantIds) {
+		t.Errorf("got title %q, writer %q, %d pages", info.Title, info.Writer, info.PageCount)
+	}
+
+	// the pages follow the archive, the bookmarks are set on the first page of the chapters
+	want := []struct {
+		typ, bookmark string
+	}{
+		{"FrontCover", ""},
+		{"", ""},
+		{"", "Chapter 1"},
+		{"", "Fight"},
+		{"", "Chapter 2"},
+	}
+	if len(info.Pages) != len(want) {
+		t.Fatalf("got %d pages in the ComicInfo.xml", len(info.Pages))
+	}
+	for i, p := range info.Pages {
+		if p.Image != i || p.Type != want[i].typ || p.Bookmark != want[i].bookmark || p.ImageWidth != 8 || p.ImageHeight != 12 {
+			t.Errorf("page %d: got %+v, want %+v", i, p, want[i])
+		}
+	}
+}
@@ -58,6 +58,11 @@ func New(options epuboptions.EPUBOptions) EPUB {
 	}
 }
 
+// kobo the EPUB is a KEPUB, rendered by the native reader of the Kobo devices
+func (e EPUB) kobo() bool {
+	return e.OutputFormat == "kepub"
+}
+
 // render templates
 func (e EPUB) render(templateString string, data map[string]any) string {
 	var result strings.Builder
@@ -75,6 +80,7 @@ func (e EPUB) writeImage(wz epubzip.EPUBZip, img epubimage.EPUBImage, zipImg *zi
 		[]byte(e.render(epubtemplates.Text, map[string]any{
 			"Title":      "Image " + utils.IntToString(img.Id) + " Part " + utils.IntToString(img.Part),
 			"ViewPort":   e.Image.View.Port(),
+			"Kobo":       e.kobo(),
 			"ImagePath":  img.ImgPath(),
 			"ImageStyle": img.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""),
 		})),
@@ -93,6 +99,7 @@ func (e EPUB) writeBlank(wz epubzip.EPUBZip, img epubimage.EPUBImage) error {
 		[]byte(e.render(epubtemplates.Blank, map[string]any{
 			"Title":    "Blank Page " + utils.IntToString(img.Id),
 			"ViewPort": e.Image.View.Port(),
+			"Kobo":     e.kobo(),
 		})),
 	)
 }
@@ -111,6 +118,7 @@ func (e EPUB) writeCoverImage(wz epubzip.EPUBZip, img epubimage.EPUBImage, part,
 		[]byte(e.render(epubtemplates.Text, map[string]any{
 			"Title":      title,
 			"ViewPort":   e.Image.View.Port(),
+			"Kobo":       e.kobo(),
 			"ImagePath":  "Images/cover." + e.Image.Format,
 			"ImageStyle": img.ImgStyle(e.Image.View.Width, e.Image.View.Height, ""),
 		})),
@@ -157,6 +165,7 @@ func (e EPUB) writeTitleImage(wz epubzip.EPUBZip, img epubimage.EPUBImage, title
 			[]byte(e.render(epubtemplates.Blank, map[string]any{
 				"Title":    "Blank Page Title",
 				"ViewPort": e.Image.View.Port(),
+				"Kobo":     e.kobo(),
 			})),
 		); err != nil {
 			return err
@@ -168,6 +177,7 @@ func (e EPUB) writeTitleImage(wz epubzip.EPUBZip, img epubimage.EPUBImage, title
 		[]byte(e.render(epubtemplates.Text, map[string]any{
 			"Title":      title,
 			"ViewPort":   e.Image.View.Port(),
+			"Kobo":       e.kobo(),
 			"ImagePath":  "Images/title." + e.Image.Format,
 			"ImageStyle": img.ImgStyle(e.Image.View.Width, e.Image.View.Height, titleAlign),
 		})),
@@ -212,6 +222,15 @@ func (e EPUB) getParts() (parts []epubPart, imgStorage epubzip.StorageImageReade
 		return images[i].Id < images[j].Id
 	})
 
+	// the cover can be marked anywhere by the ComicInfo.xml
+	coverId := e.imageProcessor.CoverId()
+	for i, img := range images {
+		if img.Id == coverId && img.Part == 0 {
+			images = append(append([]epubimage.EPUBImage{img}, images[:i]...), images[i+1:]...)
+			break
+		}
+	}
+
 	parts = make([]epubPart, 0)
 	cover := images[0]
 	if e.Image.HasCover || (cover.DoublePage && !e.Image.KeepDoublePageIfSplit) {
@@ -362,6 +381,8 @@ func (e EPUB) writePart(path string, currentPart, totalParts int, part epubPart,
 			Author:       e.Author,
 			Publisher:    e.Publisher,
 			UpdatedAt:    e.UpdatedAt,
+			ComicInfo:    e.ComicInfo,
+			Kobo:         e.kobo(),
 			ImageOptions: e.Image,
 			Cover:        part.Cover,
 			Images:       part.Images,
@@ -427,6 +448,29 @@ func (e EPUB) Write() error {
 				utils.Printf("Cover:\n%s\n", e.getTree([]epubimage.EPUBImage{p.Cover}, false))
 			}
 			utils.Printf("Files:\n%s\n", e.getTree(p.Images, false))
+			if e.Image.AutoSplitDoublePage {
+				var b strings.Builder
+				for _, img := range p.Images {
+					if !img.DoublePage {
+						continue
+					}
+					at := "middle"
+					if img.SplitOnGutter {
+						at = "gutter"
+					}
+					b.WriteString("  - " + filepath.Join(img.Path, img.Name) + ": " + utils.IntToString(img.SplitPosition) + "px (" + at + ")\n")
+				}
+				if b.Len() > 0 {
+					utils.Printf("Split:\n%s\n", b.String())
+				}
+			}
+			if skipped := e.imageProcessor.Skipped(); len(skipped) > 0 {
+				var b strings.Builder
+				for _, f := range skipped {
+					b.WriteString("  - " + filepath.Join(f.Path, f.Name) + ": " + f.Reason.Error() + "\n")
+				}
+				utils.Printf("Skipped:\n%s\n", b.String())
+			}
 		}
 		return nil
 	}
@@ -444,11 +488,26 @@ func (e EPUB) Write() error {
 		TotalJob:    2,
 		Quiet:       e.Quiet,
 		Json:        e.Json,
+		Input:       e.Input,
 	})
 
 	e.Image.View.Width, e.Image.View.Height = e.computeViewPort(epubParts)
+	write := e.writePart
+	switch e.OutputFormat {
+	case "cbz":
+		write = e.writeCBZPart
+	case "azw3":
+		write = e.writeAZW3Part
+	case "pdf":
+		write = e.writePDFPart
+	case "images":
+		write = e.writeImagesPart
+	}
 	for i, part := range epubParts {
-		ext := filepath.Ext(e.Output)
+		ext := e.OutputExt()
+		if !strings.HasSuffix(e.Output, ext) {
+			ext = filepath.Ext(e.Output)
+		}
 		suffix := ""
 		if totalParts > 1 {
 			fmtLen := utils.FormatNumberOfDigits(totalParts)
@@ -458,7 +517,7 @@ func (e EPUB) Write() error {
 
 		path := e.Output[0:len(e.Output)-len(ext)] + suffix + ext
 
-		if err := e.writePart(
+		if err := write(
 			path,
 			i+1,
 			totalParts,
@@ -471,7 +530,7 @@ func (e EPUB) Write() error {
 		_ = bar.Add(1)
 	}
 	_ = bar.Close()
-	if !e.Json {
+	if !e.Json && !e.Quiet {
 		utils.Println()
 	}
 
@@ -0,0 +1,132 @@
+package epub
+
+import (
+	"archive/zip"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+// the pages of a kepub are wrapped in kobo spans, the file has the extension of the kobo devices
+func TestWriteKepub(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "book")
+	if err := os.Mkdir(input, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, size := range []image.Point{{80, 120}, {80, 120}, {160, 120}, {80, 120}} {
+		f, err := os.Create(filepath.Join(input, string(rune('1'+i))+".png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := image.NewGray(image.Rectangle{Max: size})
+		for p := range img.Pix {
+			img.Pix[p] = testGray(i)
+		}
+		err = png.Encode(f, img)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []string{"kepub", "epub"} {
+		t.Run(format, func(t *testing.T) {
+			o := epuboptions.EPUBOptions{
+				Input:        input,
+				Title:        "Book",
+				OutputFormat: format,
+				Workers:      2,
+				Quiet:        true,
+				TitlePage:    1,
+				Image: epuboptions.Image{
+					Format:   "jpeg",
+					Quality:  90,
+					HasCover: true,
+					View:     epuboptions.View{Width: 80, Height: 120},
+				},
+			}
+			o.Output = filepath.Join(dir, "Book"+o.OutputExt())
+			if err := New(o).Write(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"kepub": "Book.kepub.epub", "epub": "Book.epub"}[format]
+			if filepath.Base(o.Output) != want {
+				t.Fatalf("got %s, want %s", filepath.Base(o.Output), want)
+			}
+			r, err := zip.OpenReader(o.Output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = r.Close()
+			}()
+
+			pages := 0
+			for _, f := range r.File {
+				if !strings.HasPrefix(f.Name, "OEBPS/Text/") || !strings.HasSuffix(f.Name, ".xhtml") {
+					continue
+				}
+				pages++
+				doc := readZipXML(t, f)
+				img := doc.FindElement("//img")
+				if img == nil {
+					// blank page, the kobo reader needs the columns for the page turns
+					if got := doc.FindElement("//div[@id='book-columns']") != nil; got != (format == "kepub") {
+						t.Errorf("%s: book columns %v", f.Name, got)
+					}
+					continue
+				}
+				span := img.Parent()
+				isKoboSpan := span.Tag == "span" && span.SelectAttrValue("class", "") == "koboSpan" && span.SelectAttrValue("id", "") == "kobo.1.1"
+				if isKoboSpan != (format == "kepub") {
+					t.Errorf("%s: image in %s with class %q", f.Name, span.Tag, span.SelectAttrValue("class", ""))
+				}
+			}
+			// cover, title, its blank page, 3 pages and the blank pages after the double page and the last page
+			if pages < 6 {
+				t.Errorf("got %d pages", pages)
+			}
+
+			for _, f := range r.File {
+				if f.Name != "OEBPS/content.opf" {
+					continue
+				}
+				spread := readZipXML(t, f).FindElement("//meta[@property='rendition:spread']").Text()
+				if want := map[string]string{"kepub": "landscape", "epub": "auto"}[format]; spread != want {
+					t.Errorf("spread: got %s, want %s", spread, want)
+				}
+			}
+		})
+	}
+}
+
+// xml file of the zip
+func readZipXML(t *testing.T, f *zip.File) *etree.Document {
+	t.Helper()
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := etree.NewDocument()
+	if err = doc.ReadFromBytes(b); err != nil {
+		t.Fatalf("%s: %v", f.Name, err)
+	}
+	return doc
+}
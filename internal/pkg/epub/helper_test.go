@@ -0,0 +1,89 @@
+package epub
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
+)
+
+// gray of the test image, each id has its own
+func testGray(id int) uint8 {
+	return uint8(0x20 + id*0x30)
+}
+
+// image of the test book, 8x12 and filled with the gray of its id
+func testImage(id int, path string) epubimage.EPUBImage {
+	return epubimage.EPUBImage{
+		Id:     id,
+		Path:   filepath.FromSlash(path),
+		Name:   string(rune('a'+id)) + ".png",
+		Format: "jpeg",
+		Width:  8,
+		Height: 12,
+		Crop:   image.Rect(0, 0, 8, 12),
+	}
+}
+
+// book with the images stored like the image processor does, the first image is the cover.
+//
+// the images of the storage are removed at the end of the test.
+func testBook(t *testing.T, hasCover bool, images ...epubimage.EPUBImage) (EPUB, epubPart, epubzip.StorageImageReader) {
+	t.Helper()
+	dir := t.TempDir()
+	e := New(epuboptions.EPUBOptions{
+		Title:  "Book",
+		Author: "Author",
+		Output: filepath.Join(dir, "book"),
+		Quiet:  true,
+		Image: epuboptions.Image{
+			Format:   "jpeg",
+			Quality:  90,
+			HasCover: hasCover,
+			View:     epuboptions.View{Width: 80, Height: 120},
+		},
+	})
+
+	w, err := epubzip.NewStorageImageWriter(e.ImgStorage(), e.Image.Format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range images {
+		raw := image.NewGray(image.Rect(0, 0, img.Width, img.Height))
+		for i := range raw.Pix {
+			raw.Pix[i] = testGray(img.Id)
+		}
+		if err = w.Add(img.EPUBImgPath(), raw, e.Image.Quality); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := epubzip.NewStorageImageReader(e.ImgStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = r.Close()
+	})
+
+	part := epubPart{Cover: images[0], Images: images}
+	if hasCover {
+		part.Images = images[1:]
+	}
+	return e, part, r
+}
+
+// check the image has the gray of the id
+func checkGray(t *testing.T, name string, img image.Image, id int) {
+	t.Helper()
+	y := color.GrayModel.Convert(img.At(4, 6)).(color.Gray).Y
+	if d := int(y) - int(testGray(id)); d < -4 || d > 4 {
+		t.Errorf("%s: got gray %#x, want %#x of the image %d", name, y, testGray(id), id)
+	}
+}
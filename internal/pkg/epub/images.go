@@ -0,0 +1,118 @@
+package epub
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+const imagesManifest = "manifest.json"
+
+// area of the original image kept in the page
+type imagesCrop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type imagesPage struct {
+	Page       int        `json:"page"`
+	File       string     `json:"file"`
+	Id         int        `json:"id"`
+	Path       string     `json:"path"`
+	Name       string     `json:"name"`
+	Part       int        `json:"part"`
+	Cover      bool       `json:"cover,omitempty"`
+	Crop       imagesCrop `json:"crop"`
+	Width      int        `json:"width"`
+	Height     int        `json:"height"`
+	Blank      bool       `json:"blank"`
+	DoublePage bool       `json:"double_page"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type imagesManifestContent struct {
+	Title      string       `json:"title"`
+	Part       int          `json:"part"`
+	TotalParts int          `json:"total_parts"`
+	Format     string       `json:"format"`
+	Pages      []imagesPage `json:"pages"`
+}
+
+// name of the exported image: page order, split part, then the original path
+func (e EPUB) imagesFileName(fmtPage string, page int, img epubimage.EPUBImage) string {
+	name := filepath.Join(img.Path, strings.TrimSuffix(img.Name, filepath.Ext(img.Name)))
+	name = strings.ReplaceAll(filepath.ToSlash(name), "/", "_")
+	return fmt.Sprintf(fmtPage, page) + "_p" + utils.IntToString(img.Part) + "_" + name + "." + img.Format
+}
+
+// write a part as a directory of the processed images, exactly as they are stored in the EPUB.
+//
+// the manifest describes each page with its original name, the crop box, the split part and the flags.
+func (e EPUB) writeImagesPart(path string, currentPart, totalParts int, part epubPart, imgStorage epubzip.StorageImageReader) error {
+	pages := part.Images
+	if e.Image.HasCover {
+		pages = append([]epubimage.EPUBImage{part.Cover}, pages...)
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	manifest := imagesManifestContent{
+		Title:      e.Title,
+		Part:       currentPart,
+		TotalParts: totalParts,
+		Format:     e.Image.Format,
+		Pages:      make([]imagesPage, 0, len(pages)),
+	}
+
+	fmtPage := utils.FormatNumberOfDigits(len(pages))
+	for i, img := range pages {
+		data, err := e.readImage(imgStorage.Get(img.EPUBImgPath()))
+		if err != nil {
+			return err
+		}
+		name := e.imagesFileName(fmtPage, i+1, img)
+		if err = os.WriteFile(filepath.Join(path, name), data, 0644); err != nil {
+			return err
+		}
+
+		page := imagesPage{
+			Page: i + 1,
+			File: name,
+			Id:   img.Id,
+			Path: img.Path,
+			Name: img.Name,
+			Part: img.Part,
+			Crop: imagesCrop{
+				X:      img.Crop.Min.X,
+				Y:      img.Crop.Min.Y,
+				Width:  img.Crop.Dx(),
+				Height: img.Crop.Dy(),
+			},
+			Cover:      i == 0 && e.Image.HasCover,
+			Width:      img.Width,
+			Height:     img.Height,
+			Blank:      img.IsBlank,
+			DoublePage: img.DoublePage,
+		}
+		if img.Error != nil {
+			page.Error = img.Error.Error()
+		}
+		manifest.Pages = append(manifest.Pages, page)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, imagesManifest), b, 0644)
+}
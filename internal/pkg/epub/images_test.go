@@ -0,0 +1,70 @@
+package epub
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteImagesPart(t *testing.T) {
+	split := testImage(1, "Chapter 1")
+	split.Part, split.DoublePage, split.Crop = 1, true, image.Rect(4, 2, 12, 14)
+	blank := testImage(2, "Chapter 1/Scene 2")
+	blank.IsBlank, blank.Error = true, errors.New("corrupted")
+	e, part, storage := testBook(t, true, testImage(0, ""), split, blank)
+	if err := e.writeImagesPart(e.Output, 2, 3, part, storage); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(e.Output, imagesManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest imagesManifestContent
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	want := imagesManifestContent{
+		Title:      "Book",
+		Part:       2,
+		TotalParts: 3,
+		Format:     "jpeg",
+		Pages: []imagesPage{
+			{
+				Page: 1, File: "1_p0_a.jpeg", Id: 0, Name: "a.png", Cover: true,
+				Crop: imagesCrop{0, 0, 8, 12}, Width: 8, Height: 12,
+			},
+			{
+				Page: 2, File: "2_p1_Chapter 1_b.jpeg", Id: 1, Path: filepath.FromSlash("Chapter 1"), Name: "b.png", Part: 1,
+				Crop: imagesCrop{4, 2, 8, 12}, Width: 8, Height: 12, DoublePage: true,
+			},
+			{
+				Page: 3, File: "3_p0_Chapter 1_Scene 2_c.jpeg", Id: 2, Path: filepath.FromSlash("Chapter 1/Scene 2"), Name: "c.png",
+				Crop: imagesCrop{0, 0, 8, 12}, Width: 8, Height: 12, Blank: true, Error: "corrupted",
+			},
+		},
+	}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Errorf("got %+v\nwant %+v", manifest, want)
+	}
+
+	// the files of the manifest are the stored images
+	for _, p := range manifest.Pages {
+		f, err := os.Open(filepath.Join(e.Output, p.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, _, err := image.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", p.File, err)
+		}
+		checkGray(t, p.File, img, p.Id)
+	}
+}
@@ -0,0 +1,137 @@
+package epub
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubtree"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+// outline of the PDF, linked to the first page of the directory
+type pdfOutline struct {
+	Title string
+	Level int
+	Path  string
+}
+
+// outlines of the directories, in the order of the tree used for the toc
+func (e EPUB) pdfOutlines(images []epubimage.EPUBImage) []pdfOutline {
+	t := epubtree.New()
+	for _, img := range images {
+		t.Add(img.Path)
+	}
+	root, rootPath := t.Root(), "."
+	if e.StripFirstDirectoryFromToc && root.ChildCount() == 1 {
+		root = root.FirstChild()
+		rootPath = root.Value()
+	}
+
+	var (
+		outlines []pdfOutline
+		walk     func(n *epubtree.Node, path string, level int)
+	)
+	walk = func(n *epubtree.Node, path string, level int) {
+		for _, c := range n.Children() {
+			p := filepath.Join(path, c.Value())
+			outlines = append(outlines, pdfOutline{c.Value(), level, p})
+			walk(c, p, level+1)
+		}
+	}
+	walk(root, rootPath, 0)
+	return outlines
+}
+
+// pdf text string, encoded in UTF-16 with a BOM to support any character
+func (e EPUB) pdfText(s string) string {
+	var b strings.Builder
+	b.WriteString("\xfe\xff")
+	for _, c := range utf16.Encode([]rune(s)) {
+		b.WriteByte(byte(c >> 8))
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
+
+// write a part as a PDF, one page per image with the size of the view.
+//
+// the images are embedded as they are, the outlines follow the directories and the bookmarks.
+func (e EPUB) writePDFPart(path string, currentPart, totalParts int, part epubPart, imgStorage epubzip.StorageImageReader) error {
+	title := e.Title
+	if totalParts > 1 {
+		title = title + " [" + utils.IntToString(currentPart) + "/" + utils.IntToString(totalParts) + "]"
+	}
+
+	pages := part.Images
+	if e.Image.HasCover {
+		pages = append([]epubimage.EPUBImage{part.Cover}, pages...)
+	}
+
+	viewWidth, viewHeight := e.Image.View.Width, e.Image.View.Height
+	size := gofpdf.SizeType{Wd: float64(viewWidth), Ht: float64(viewHeight)}
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "pt", Size: size})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(e.Author, true)
+	pdf.SetCreator(e.Publisher, true)
+	pdf.SetCreationDate(time.Now())
+	if e.ComicInfo.Summary != "" {
+		pdf.SetSubject(e.ComicInfo.Summary, true)
+	}
+	if genres := e.ComicInfo.Genres(); len(genres) > 0 {
+		pdf.SetKeywords(strings.Join(genres, ", "), true)
+	}
+
+	imageType := "JPG"
+	if e.Image.Format == "png" {
+		imageType = "PNG"
+	}
+
+	outlines := e.pdfOutlines(part.Images)
+	levels := map[string]int{}
+	bookmarks := map[int]bool{}
+	for i, img := range pages {
+		data, err := e.readImage(imgStorage.Get(img.EPUBImgPath()))
+		if err != nil {
+			return err
+		}
+
+		pdf.AddPageFormat("P", size)
+
+		// outlines of the directories starting on this page, the cover is not part of them
+		imgPath := filepath.Clean(img.Path)
+		for len(outlines) > 0 && (i > 0 || !e.Image.HasCover) {
+			o := outlines[0]
+			if o.Path != imgPath && !strings.HasPrefix(imgPath, o.Path+string(filepath.Separator)) {
+				break
+			}
+			pdf.Bookmark(e.pdfText(o.Title), o.Level, 0)
+			levels[o.Path] = o.Level
+			outlines = outlines[1:]
+		}
+		if img.Bookmark != "" && !bookmarks[img.Id] {
+			bookmarks[img.Id] = true
+			level := 0
+			if l, ok := levels[imgPath]; ok {
+				level = l + 1
+			}
+			pdf.Bookmark(e.pdfText(img.Bookmark), level, 0)
+		}
+
+		name := img.ImgKey()
+		opt := gofpdf.ImageOptions{ImageType: imageType}
+		pdf.RegisterImageOptionsReader(name, opt, bytes.NewReader(data))
+		w, h := img.RelSize(viewWidth, viewHeight)
+		pdf.ImageOptions(name, float64(viewWidth-w)/2, float64(viewHeight-h)/2, float64(w), float64(h), false, opt, 0, "")
+	}
+
+	return pdf.OutputFileAndClose(path)
+}
@@ -0,0 +1,125 @@
+package epub
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/raff/pdfreader/pdfread"
+	"github.com/raff/pdfreader/ps"
+)
+
+// outline read back from the PDF, with the index of its page
+type pdfTestOutline struct {
+	Title string
+	Level int
+	Page  int
+}
+
+// outlines of the PDF, depth first
+func readPdfOutlines(t *testing.T, pdf *pdfread.PdfReaderT) []pdfTestOutline {
+	t.Helper()
+	pages := pdf.Pages()
+	var (
+		outlines []pdfTestOutline
+		walk     func(o pdfread.DictionaryT, level int)
+	)
+	walk = func(o pdfread.DictionaryT, level int) {
+		for ; o != nil; o = pdf.Dic(o["/Next"]) {
+			title := ps.String(o["/Title"])
+			if !bytes.HasPrefix(title, []byte("\xfe\xff")) {
+				t.Fatalf("title %q is not utf-16", title)
+			}
+			u := make([]uint16, 0, len(title)/2)
+			for i := 2; i+1 < len(title); i += 2 {
+				u = append(u, uint16(title[i])<<8|uint16(title[i+1]))
+			}
+			page := -1
+			if dest := pdf.Arr(o["/Dest"]); len(dest) > 0 {
+				for i, p := range pages {
+					if bytes.Equal(p, dest[0]) {
+						page = i
+					}
+				}
+			}
+			outlines = append(outlines, pdfTestOutline{string(utf16.Decode(u)), level, page})
+			if first, ok := o["/First"]; ok {
+				walk(pdf.Dic(first), level+1)
+			}
+		}
+	}
+	if root := pdf.Dic(pdf.Dic(pdf.Trailer["/Root"])["/Outlines"]); root != nil {
+		walk(pdf.Dic(root["/First"]), 0)
+	}
+	return outlines
+}
+
+func TestWritePDFPart(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		hasCover bool
+		root     string
+		strip    bool
+		pages    int
+		want     []pdfTestOutline
+	}{
+		{
+			name: "cover", hasCover: true, pages: 5,
+			want: []pdfTestOutline{
+				{"Chapter 1", 0, 1},
+				{"Scène 1", 1, 2},
+				{"Fight", 2, 3},
+				{"Chapter 2", 0, 4},
+			},
+		},
+		{
+			name: "without cover", root: "Book/", pages: 5,
+			want: []pdfTestOutline{
+				{"Book", 0, 0},
+				{"Chapter 1", 1, 1},
+				{"Scène 1", 2, 2},
+				{"Fight", 3, 3},
+				{"Chapter 2", 1, 4},
+			},
+		},
+		{
+			name: "first directory stripped", root: "Book/", strip: true, pages: 5,
+			want: []pdfTestOutline{
+				{"Chapter 1", 0, 1},
+				{"Scène 1", 1, 2},
+				{"Fight", 2, 3},
+				{"Chapter 2", 0, 4},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bookmarked := testImage(3, tc.root+"Chapter 1/Scène 1")
+			bookmarked.Bookmark = "Fight"
+			e, part, storage := testBook(t, tc.hasCover,
+				testImage(0, tc.root),
+				testImage(1, tc.root+"Chapter 1"),
+				testImage(2, tc.root+"Chapter 1/Scène 1"),
+				bookmarked,
+				testImage(4, tc.root+"Chapter 2"),
+			)
+			e.StripFirstDirectoryFromToc = tc.strip
+			path := e.Output + ".pdf"
+			if err := e.writePDFPart(path, 1, 1, part, storage); err != nil {
+				t.Fatal(err)
+			}
+
+			pdf := pdfread.Load(path)
+			if pdf == nil {
+				t.Fatal("can't read pdf")
+			}
+			defer pdf.Close()
+			if got := len(pdf.Pages()); got != tc.pages {
+				t.Errorf("got %d pages, want %d", got, tc.pages)
+			}
+			if got := readPdfOutlines(t, pdf); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
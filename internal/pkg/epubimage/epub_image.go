@@ -18,6 +18,10 @@ type EPUBImage struct {
 	DoublePage          bool
 	Path                string
 	Name                string
+	Bookmark            string
+	Crop                image.Rectangle
+	SplitPosition       int
+	SplitOnGutter       bool
 	Position            string
 	Format              string
 	OriginalAspectRatio float64
@@ -7,17 +7,56 @@ import (
 	"github.com/disintegration/gift"
 )
 
+// color of the margins
+const (
+	BorderWhite = iota
+	BorderBlack
+	BorderAuto
+)
+
+const (
+	// maximum size of an artifact, in percentage of the area: across the edge and along it
+	artifactMaxSize   = 5
+	artifactMaxLength = 25
+	// part of the area, on each side, where the artifacts are looked up
+	artifactSearch = 20
+)
+
 // AutoCrop Lookup for margin and crop
-func AutoCrop(img image.Image, bounds image.Rectangle, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int, limit int, skipIfLimitReached bool) gift.Filter {
+func AutoCrop(img image.Image, bounds image.Rectangle, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int, limit int, skipIfLimitReached bool, border, threshold int, ignoreArtifacts bool) gift.Filter {
 	return gift.Crop(
-		findMargin(img, bounds, cutRatioOptions{cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom}, limit, skipIfLimitReached),
+		AutoCropBounds(img, bounds, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom, limit, skipIfLimitReached, border, threshold, ignoreArtifacts),
 	)
 }
 
-// check if the color is blank enough
-func colorIsBlank(c color.Color) bool {
+// AutoCropBounds Lookup for margin, return the area to keep in the coordinates of the image
+//
+// The margins are white, black, or in auto mode the color of the outer line of each side.
+// The threshold is the tolerance of the luminance of the margins.
+// With ignoreArtifacts, the small marks isolated near the edges, like the page numbers, are cropped with the margins.
+func AutoCropBounds(img image.Image, bounds image.Rectangle, cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom int, limit int, skipIfLimitReached bool, border, threshold int, ignoreArtifacts bool) image.Rectangle {
+	return findMargin(img, bounds, cutRatioOptions{cutRatioLeft, cutRatioUp, cutRatioRight, cutRatioBottom}, limit, skipIfLimitReached, border, threshold, ignoreArtifacts)
+}
+
+// check if the color is blank enough: close to white, or close to black for the dark margins
+func colorIsBlank(c color.Color, dark bool, threshold int) bool {
 	g := color.GrayModel.Convert(c).(color.Gray)
-	return g.Y >= 0xe0
+	if dark {
+		return int(g.Y) <= threshold
+	}
+	return int(g.Y) >= 0xff-threshold
+}
+
+// check if the outer line of the area, from a to b, is dark
+func lineIsDark(img image.Image, a, b image.Point) bool {
+	var sum, count int
+	for x := a.X; x <= b.X; x++ {
+		for y := a.Y; y <= b.Y; y++ {
+			sum += int(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			count++
+		}
+	}
+	return count > 0 && sum/count < 0x80
 }
 
 // lookup for margin (blank) around the image
@@ -25,14 +64,30 @@ type cutRatioOptions struct {
 	Left, Up, Right, Bottom int
 }
 
-func findMargin(img image.Image, bounds image.Rectangle, cutRatio cutRatioOptions, limit int, skipIfLimitReached bool) image.Rectangle {
+func findMargin(img image.Image, bounds image.Rectangle, cutRatio cutRatioOptions, limit int, skipIfLimitReached bool, border, threshold int, ignoreArtifacts bool) image.Rectangle {
 	imgArea := bounds
+	if imgArea.Empty() {
+		return imgArea
+	}
+
+	// color of the margin of each side
+	var darkLeft, darkUp, darkRight, darkBottom bool
+	switch border {
+	case BorderBlack:
+		darkLeft, darkUp, darkRight, darkBottom = true, true, true, true
+	case BorderAuto:
+		last := imgArea.Max.Sub(image.Pt(1, 1))
+		darkLeft = lineIsDark(img, imgArea.Min, image.Pt(imgArea.Min.X, last.Y))
+		darkUp = lineIsDark(img, imgArea.Min, image.Pt(last.X, imgArea.Min.Y))
+		darkRight = lineIsDark(img, image.Pt(last.X, imgArea.Min.Y), last)
+		darkBottom = lineIsDark(img, image.Pt(imgArea.Min.X, last.Y), last)
+	}
 
 LEFT:
 	for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
 		allowNonBlank := imgArea.Dy() * cutRatio.Left / 100
 		for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), darkLeft, threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break LEFT
@@ -46,7 +101,7 @@ UP:
 	for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
 		allowNonBlank := imgArea.Dx() * cutRatio.Up / 100
 		for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), darkUp, threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break UP
@@ -60,7 +115,7 @@ RIGHT:
 	for x := imgArea.Max.X - 1; x >= imgArea.Min.X; x-- {
 		allowNonBlank := imgArea.Dy() * cutRatio.Right / 100
 		for y := imgArea.Min.Y; y < imgArea.Max.Y; y++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), darkRight, threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break RIGHT
@@ -74,7 +129,7 @@ BOTTOM:
 	for y := imgArea.Max.Y - 1; y >= imgArea.Min.Y; y-- {
 		allowNonBlank := imgArea.Dx() * cutRatio.Bottom / 100
 		for x := imgArea.Min.X; x < imgArea.Max.X; x++ {
-			if !colorIsBlank(img.At(x, y)) {
+			if !colorIsBlank(img.At(x, y), darkBottom, threshold) {
 				allowNonBlank--
 				if allowNonBlank <= 0 {
 					break BOTTOM
@@ -84,6 +139,19 @@ BOTTOM:
 		imgArea.Max.Y--
 	}
 
+	if ignoreArtifacts && !imgArea.Empty() {
+		anyDark := darkLeft || darkUp || darkRight || darkBottom
+		anyWhite := !(darkLeft && darkUp && darkRight && darkBottom)
+		m := newInkMask(img, imgArea, func(c color.Color) bool {
+			return (anyWhite && colorIsBlank(c, false, threshold)) || (anyDark && colorIsBlank(c, true, threshold))
+		})
+		// removing the marks on one axis can isolate others on the other axis
+		for range 2 {
+			imgArea = m.dropArtifacts(imgArea, false)
+			imgArea = m.dropArtifacts(imgArea, true)
+		}
+	}
+
 	// no limit or blankImage
 	if limit == 0 || imgArea.Dx() == 0 || imgArea.Dy() == 0 {
 		return imgArea
@@ -121,3 +189,85 @@ func correctLine(min, max, bMin, bMax, exceed int) (int, int) {
 	}
 	return min, max
 }
+
+// pixels of the area that are not blank
+type inkMask struct {
+	area image.Rectangle
+	ink  []bool
+}
+
+func newInkMask(img image.Image, area image.Rectangle, blank func(c color.Color) bool) inkMask {
+	m := inkMask{area, make([]bool, area.Dx()*area.Dy())}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			m.ink[(y-area.Min.Y)*area.Dx()+x-area.Min.X] = !blank(img.At(x, y))
+		}
+	}
+	return m
+}
+
+func (m inkMask) at(x, y int) bool {
+	return m.ink[(y-m.area.Min.Y)*m.area.Dx()+x-m.area.Min.X]
+}
+
+// drop the small blocks of ink isolated at both ends of the area: the rows, or the columns.
+//
+// the lines with ink are grouped in blocks, the small gaps are merged.
+// the outer blocks small in both directions are dropped, the main content is always kept.
+func (m inkMask) dropArtifacts(area image.Rectangle, columns bool) image.Rectangle {
+	lo, hi, acrossLo, acrossHi := area.Min.Y, area.Max.Y, area.Min.X, area.Max.X
+	at := func(i, j int) bool { return m.at(j, i) }
+	if columns {
+		lo, hi, acrossLo, acrossHi = area.Min.X, area.Max.X, area.Min.Y, area.Max.Y
+		at = m.at
+	}
+	size, length := hi-lo, acrossHi-acrossLo
+
+	// lines [from, to) with the ink between min and max
+	type block struct {
+		from, to, min, max int
+	}
+	gap := max(2, size/200)
+	var blocks []block
+	for i := lo; i < hi; i++ {
+		bMin, bMax := acrossHi, acrossLo
+		for j := acrossLo; j < acrossHi; j++ {
+			if at(i, j) {
+				bMin, bMax = min(bMin, j), max(bMax, j+1)
+			}
+		}
+		if bMin >= bMax {
+			continue
+		}
+		if n := len(blocks); n > 0 && i-blocks[n-1].to < gap {
+			b := &blocks[n-1]
+			b.to, b.min, b.max = i+1, min(b.min, bMin), max(b.max, bMax)
+		} else {
+			blocks = append(blocks, block{i, i + 1, bMin, bMax})
+		}
+	}
+
+	isArtifact := func(b block) bool {
+		return (b.to-b.from)*100 <= size*artifactMaxSize && (b.max-b.min)*100 <= length*artifactMaxLength
+	}
+	first, last := 0, len(blocks)-1
+	for first < last && isArtifact(blocks[first]) && (blocks[first].to-lo)*100 <= size*artifactSearch {
+		first++
+	}
+	for last > first && isArtifact(blocks[last]) && (hi-blocks[last].from)*100 <= size*artifactSearch {
+		last--
+	}
+	if first > 0 {
+		lo = blocks[first].from
+	}
+	if last >= 0 && last < len(blocks)-1 {
+		hi = blocks[last].to
+	}
+
+	if columns {
+		area.Min.X, area.Max.X = lo, hi
+	} else {
+		area.Min.Y, area.Max.Y = lo, hi
+	}
+	return area
+}
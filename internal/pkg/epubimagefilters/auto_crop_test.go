@@ -0,0 +1,107 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestAutoCropBorder(t *testing.T) {
+	content := image.Rect(40, 50, 160, 250)
+	for _, tc := range []struct {
+		name       string
+		img        image.Image
+		border     int
+		threshold  int
+		wantBounds image.Rectangle
+	}{
+		{"white margins", testPage(200, 300, 0xff, 0x40, content), BorderWhite, 31, content},
+		{"black margins", testPage(200, 300, 0x00, 0xc0, content), BorderBlack, 31, content},
+		{"black margins with the white border", testPage(200, 300, 0x00, 0xc0, content), BorderWhite, 31, image.Rect(0, 0, 200, 300)},
+		{"auto with black margins", testPage(200, 300, 0x00, 0xc0, content), BorderAuto, 31, content},
+		{"auto with white margins", testPage(200, 300, 0xff, 0x40, content), BorderAuto, 31, content},
+		{"gray margins within the threshold", testPage(200, 300, 0x20, 0xc0, content), BorderBlack, 0x20, content},
+		{"gray margins over the threshold", testPage(200, 300, 0x30, 0xc0, content), BorderBlack, 0x20, image.Rect(0, 0, 200, 300)},
+		{
+			// dark on the left, white on the right, like a scan with the shadow of the binding
+			"auto with mixed margins",
+			func() image.Image {
+				img := testPage(200, 300, 0xff, 0x80, content)
+				draw.Draw(img, image.Rect(0, 0, 20, 300), image.NewUniform(color.Gray{}), image.Point{}, draw.Src)
+				return img
+			}(),
+			BorderAuto, 31, image.Rect(20, 50, 160, 250),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := AutoCropBounds(tc.img, tc.img.Bounds(), 0, 0, 0, 0, 0, false, tc.border, tc.threshold, false)
+			if got != tc.wantBounds {
+				t.Errorf("got %v, want %v", got, tc.wantBounds)
+			}
+		})
+	}
+}
+
+func TestDropArtifacts(t *testing.T) {
+	content := image.Rect(40, 50, 160, 250)
+	for _, tc := range []struct {
+		name    string
+		marks   []image.Rectangle
+		columns bool
+		want    image.Rectangle
+	}{
+		{"no mark", nil, false, content},
+		{"page number at the bottom", []image.Rectangle{image.Rect(95, 280, 105, 286)}, false, content},
+		{"page number at the top", []image.Rectangle{image.Rect(95, 10, 105, 16)}, false, content},
+		{"marks at both ends", []image.Rectangle{image.Rect(95, 10, 105, 16), image.Rect(50, 290, 60, 295)}, false, content},
+		{"2 marks at the bottom", []image.Rectangle{image.Rect(95, 270, 105, 275), image.Rect(95, 290, 105, 295)}, false, content},
+		{"mark on the side", []image.Rectangle{image.Rect(190, 140, 196, 150)}, true, content},
+		{"mark on the side, looking at the rows", []image.Rectangle{image.Rect(190, 140, 196, 150)}, false, image.Rect(40, 50, 196, 250)},
+		{"wide block", []image.Rectangle{image.Rect(50, 280, 150, 286)}, false, image.Rect(40, 50, 160, 286)},
+		{"tall block", []image.Rectangle{image.Rect(95, 260, 105, 290)}, false, image.Rect(40, 50, 160, 290)},
+		{"mark next to the content", []image.Rectangle{image.Rect(95, 251, 105, 256)}, false, image.Rect(40, 50, 160, 256)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			img := testPage(200, 300, 0xff, 0, append([]image.Rectangle{content}, tc.marks...)...)
+			m := newInkMask(img, img.Bounds(), func(c color.Color) bool {
+				return colorIsBlank(c, false, 31)
+			})
+			// the margins are already removed
+			area := content
+			for _, r := range tc.marks {
+				area = area.Union(r)
+			}
+			if got := m.dropArtifacts(area, tc.columns); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// the page only has a small mark, it isn't blank
+func TestDropArtifactsKeepContent(t *testing.T) {
+	img := testPage(200, 300, 0xff, 0, image.Rect(95, 280, 105, 286))
+	m := newInkMask(img, img.Bounds(), func(c color.Color) bool {
+		return colorIsBlank(c, false, 31)
+	})
+	if got, want := m.dropArtifacts(img.Bounds(), false), img.Bounds(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAutoCropIgnoreArtifacts(t *testing.T) {
+	content := image.Rect(40, 50, 160, 250)
+	img := testPage(200, 300, 0xff, 0, content, image.Rect(95, 280, 105, 286), image.Rect(190, 140, 196, 150))
+	for _, tc := range []struct {
+		ignore bool
+		want   image.Rectangle
+	}{
+		{false, image.Rect(40, 50, 196, 286)},
+		{true, content},
+	} {
+		if got := AutoCropBounds(img, img.Bounds(), 0, 0, 0, 0, 0, false, BorderWhite, 31, tc.ignore); got != tc.want {
+			t.Errorf("ignore artifacts %v: got %v, want %v", tc.ignore, got, tc.want)
+		}
+	}
+}
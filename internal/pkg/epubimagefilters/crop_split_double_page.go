@@ -2,32 +2,46 @@ package epubimagefilters
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
+	"math"
 
 	"github.com/disintegration/gift"
 )
 
+const (
+	// part of the width, around the middle, where the gutter is looked up
+	gutterSearchRatio = 10
+	// maximum standard deviation of the luminance of a uniform band
+	gutterUniformTolerance = 16
+)
+
 // CropSplitDoublePage Cut a double page in 2 part: left and right.
 //
-// This will cut in the middle of the page.
-func CropSplitDoublePage(right bool) gift.Filter {
-	return cropSplitDoublePage{right}
+// This will cut at the position from the left of the page, in the middle if the position is not set.
+func CropSplitDoublePage(right bool, position int) gift.Filter {
+	return cropSplitDoublePage{right, position}
 }
 
 type cropSplitDoublePage struct {
-	right bool
+	right    bool
+	position int
 }
 
 func (p cropSplitDoublePage) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	x := srcBounds.Min.X + srcBounds.Dx()/2
+	if p.position > 0 && p.position < srcBounds.Dx() {
+		x = srcBounds.Min.X + p.position
+	}
 	if p.right {
 		dstBounds = image.Rect(
-			srcBounds.Max.X/2, srcBounds.Min.Y,
+			x, srcBounds.Min.Y,
 			srcBounds.Max.X, srcBounds.Max.Y,
 		)
 	} else {
 		dstBounds = image.Rect(
 			srcBounds.Min.X, srcBounds.Min.Y,
-			srcBounds.Max.X/2, srcBounds.Max.Y,
+			x, srcBounds.Max.Y,
 		)
 	}
 	return
@@ -36,3 +50,76 @@ func (p cropSplitDoublePage) Bounds(srcBounds image.Rectangle) (dstBounds image.
 func (p cropSplitDoublePage) Draw(dst draw.Image, src image.Image, options *gift.Options) {
 	gift.Crop(dst.Bounds()).Draw(dst, src, options)
 }
+
+// SplitDoublePagePosition Lookup for the gutter of a double page, around the middle of the bounds.
+//
+// The gutter is the most uniform vertical band, or the lightest one.
+// It returns the position from the left of the bounds, and false with the middle if no gutter is found.
+func SplitDoublePagePosition(img image.Image, bounds image.Rectangle) (int, bool) {
+	middle := bounds.Dx() / 2
+	search := bounds.Dx() * gutterSearchRatio / 100
+	if search == 0 || bounds.Dy() == 0 {
+		return middle, false
+	}
+
+	// luminance of the columns, on a sample of the rows
+	step := max(1, bounds.Dy()/512)
+	n := float64((bounds.Dy() + step - 1) / step)
+	from, to := middle-search, middle+search
+	mean := make([]float64, to-from)
+	stdDev := make([]float64, to-from)
+	for i := range mean {
+		x := bounds.Min.X + from + i
+		var sum, sum2 float64
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			l := float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			sum += l
+			sum2 += l * l
+		}
+		mean[i] = sum / n
+		stdDev[i] = math.Sqrt(max(0, sum2/n-mean[i]*mean[i]))
+	}
+
+	// center of the band of matching columns closest to the middle
+	closest := func(match func(i int) bool) (int, bool) {
+		best, found := 0, false
+		for i := 0; i < len(mean); {
+			if !match(i) {
+				i++
+				continue
+			}
+			j := i
+			for j < len(mean) && match(j) {
+				j++
+			}
+			center := from + (i+j)/2
+			if !found || abs(center-middle) < abs(best-middle) {
+				best, found = center, true
+			}
+			i = j
+		}
+		return best, found
+	}
+
+	if pos, ok := closest(func(i int) bool { return stdDev[i] <= gutterUniformTolerance }); ok {
+		return pos, true
+	}
+
+	// the art can cross the gutter, take the lightest band if it is blank enough
+	lightest := 0.0
+	for _, m := range mean {
+		lightest = max(lightest, m)
+	}
+	if lightest >= 0xe0 {
+		return closest(func(i int) bool { return mean[i] >= lightest-1 })
+	}
+
+	return middle, false
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,89 @@
+package epubimagefilters
+
+import (
+	"image"
+	"testing"
+)
+
+// columns [from, to) filled by the function
+type band struct {
+	from, to int
+	fill     func(x, y int) uint8
+}
+
+// double page with art everywhere except the bands
+func testDoublePage(bounds image.Rectangle, bands ...band) *image.Gray {
+	return testImage(bounds, func(x, y int) uint8 {
+		for _, b := range bands {
+			if x-bounds.Min.X >= b.from && x-bounds.Min.X < b.to {
+				return b.fill(x, y)
+			}
+		}
+		return art(x, y)
+	})
+}
+
+func white(int, int) uint8 { return 0xff }
+
+func gray(int, int) uint8 { return 0x80 }
+
+// white with some art crossing the gutter
+func crossed(_, y int) uint8 {
+	if y%10 == 0 {
+		return 0
+	}
+	return 0xff
+}
+
+func TestSplitDoublePagePosition(t *testing.T) {
+	full := image.Rect(0, 0, 400, 300)
+	for _, tc := range []struct {
+		name   string
+		img    image.Image
+		bounds image.Rectangle
+		pos    int
+		found  bool
+	}{
+		{"white gutter off the middle", testDoublePage(full, band{215, 225, white}), full, 220, true},
+		{"gray gutter", testDoublePage(full, band{180, 190, gray}), full, 185, true},
+		{"closest gutter to the middle", testDoublePage(full, band{165, 170, white}, band{205, 215, white}), full, 210, true},
+		{"art crossing the gutter", testDoublePage(full, band{190, 196, crossed}), full, 193, true},
+		{"gutter too far from the middle", testDoublePage(full, band{300, 310, white}), full, 200, false},
+		{"no gutter", testDoublePage(full), full, 200, false},
+		{"blank page", testDoublePage(full, band{0, 400, white}), full, 200, true},
+		{
+			"bounds of a cropped page",
+			testDoublePage(image.Rect(0, 0, 500, 300), band{315, 325, white}),
+			image.Rect(100, 0, 500, 300),
+			220, true,
+		},
+		{"empty bounds", testDoublePage(full), image.Rect(0, 0, 0, 0), 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pos, found := SplitDoublePagePosition(tc.img, tc.bounds)
+			if pos != tc.pos || found != tc.found {
+				t.Errorf("got %d %v, want %d %v", pos, found, tc.pos, tc.found)
+			}
+		})
+	}
+}
+
+func TestCropSplitDoublePageBounds(t *testing.T) {
+	src := image.Rect(100, 0, 500, 300)
+	for _, tc := range []struct {
+		right    bool
+		position int
+		want     image.Rectangle
+	}{
+		{false, 0, image.Rect(100, 0, 300, 300)},
+		{true, 0, image.Rect(300, 0, 500, 300)},
+		{false, 220, image.Rect(100, 0, 320, 300)},
+		{true, 220, image.Rect(320, 0, 500, 300)},
+		// out of the bounds, the middle is used
+		{true, 400, image.Rect(300, 0, 500, 300)},
+	} {
+		if got := CropSplitDoublePage(tc.right, tc.position).Bounds(src); got != tc.want {
+			t.Errorf("right %v, position %d: got %v, want %v", tc.right, tc.position, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/gift"
+)
+
+// threshold map of the ordered dithering
+var bayer8 = [8][8]float64{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// GrayPalette Palette with the levels of gray, evenly spread from black to white.
+func GrayPalette(levels int) color.Palette {
+	p := make(color.Palette, levels)
+	for i := range p {
+		p[i] = color.Gray{Y: uint8(i * 255 / (levels - 1))}
+	}
+	return p
+}
+
+// FloydSteinberg Quantize the image to levels of gray, the error is diffused to the next pixels.
+func FloydSteinberg(levels int) gift.Filter {
+	return dither{levels, false}
+}
+
+// OrderedDither Quantize the image to levels of gray, with a bayer threshold map.
+//
+// The pattern is regular, and doesn't move between similar images.
+func OrderedDither(levels int) gift.Filter {
+	return dither{levels, true}
+}
+
+type dither struct {
+	levels  int
+	ordered bool
+}
+
+// Bounds calculates the appropriate bounds of an image after applying the filter.
+func (d dither) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	dstBounds = srcBounds
+	return
+}
+
+// closest level of the luminance
+func (d dither) quantize(v float64) float64 {
+	step := 255 / float64(d.levels-1)
+	return math.Round(min(255, max(0, v))/step) * step
+}
+
+// Draw into the dst after applying the filter
+func (d dither) Draw(dst draw.Image, src image.Image, _ *gift.Options) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]float64, w*h)
+	for y := range h {
+		for x := range w {
+			lum[y*w+x] = float64(color.GrayModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y)
+		}
+	}
+
+	step := 255 / float64(d.levels-1)
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			old := lum[i]
+			if d.ordered {
+				lum[i] = d.quantize(old + (bayer8[y%8][x%8]/64-0.5)*step)
+				continue
+			}
+			lum[i] = d.quantize(old)
+			e := old - lum[i]
+			if x+1 < w {
+				lum[i+1] += e * 7 / 16
+			}
+			if y+1 < h {
+				if x > 0 {
+					lum[i+w-1] += e * 3 / 16
+				}
+				lum[i+w] += e * 5 / 16
+				if x+1 < w {
+					lum[i+w+1] += e * 1 / 16
+				}
+			}
+		}
+	}
+
+	db := dst.Bounds()
+	for y := range h {
+		for x := range w {
+			dst.Set(db.Min.X+x, db.Min.Y+y, color.Gray{Y: uint8(lum[y*w+x])})
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGrayPalette(t *testing.T) {
+	p := GrayPalette(4)
+	want := []uint8{0, 85, 170, 255}
+	if len(p) != len(want) {
+		t.Fatalf("got %d levels, want %d", len(p), len(want))
+	}
+	for i, c := range p {
+		if c.(color.Gray).Y != want[i] {
+			t.Errorf("level %d: got %v, want %d", i, c, want[i])
+		}
+	}
+}
+
+func TestDither(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		levels  int
+		ordered bool
+	}{
+		{"floyd steinberg", 4, false},
+		{"floyd steinberg black and white", 2, false},
+		{"ordered", 4, true},
+		{"ordered black and white", 2, true},
+		{"floyd steinberg 16 levels", 16, false},
+		{"ordered 16 levels", 16, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := FloydSteinberg(tc.levels)
+			if tc.ordered {
+				filter = OrderedDither(tc.levels)
+			}
+			palette := map[uint8]bool{}
+			for _, c := range GrayPalette(tc.levels) {
+				palette[c.(color.Gray).Y] = true
+			}
+
+			// only the levels of the palette are used, all of them on a gradient
+			used := map[uint8]bool{}
+			for _, v := range applyFilter(filter, testGradient()).Pix {
+				if !palette[v] {
+					t.Fatalf("level %d is not in the palette", v)
+				}
+				used[v] = true
+			}
+			if len(used) != tc.levels {
+				t.Errorf("got %d levels, want %d", len(used), tc.levels)
+			}
+
+			// a flat gray keeps its luminance on average
+			for _, in := range []uint8{0x00, 0x30, 0x60, 0x80, 0xd0, 0xff} {
+				src := testPage(64, 64, in, 0)
+				var sum int
+				for _, v := range applyFilter(filter, src).Pix {
+					sum += int(v)
+				}
+				if mean := sum / len(src.Pix); absDiff(uint8(mean), in) > 4 {
+					t.Errorf("%#x: got a mean of %#x", in, mean)
+				}
+			}
+		})
+	}
+}
+
+// the pattern of the ordered dither repeat every 8 pixels
+func TestOrderedDitherPattern(t *testing.T) {
+	dst := applyFilter(OrderedDither(4), testPage(32, 32, 0x70, 0))
+	for y := range 24 {
+		for x := range 24 {
+			if v := dst.GrayAt(x, y); v != dst.GrayAt(x+8, y) || v != dst.GrayAt(x, y+8) {
+				t.Fatalf("%d,%d: the pattern doesn't repeat", x, y)
+			}
+		}
+	}
+}
+
+// a gray of the palette is left untouched
+func TestDitherExactLevel(t *testing.T) {
+	src := testPage(16, 16, 85, 0)
+	for _, ordered := range []bool{false, true} {
+		filter := FloydSteinberg(4)
+		if ordered {
+			filter = OrderedDither(4)
+		}
+		for _, v := range applyFilter(filter, src).Pix {
+			if v != 85 {
+				t.Fatalf("ordered %v: got %d", ordered, v)
+			}
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		black, white int
+		gamma        float64
+		// expected output by input level
+		want map[uint8]uint8
+	}{
+		{"identity", 0, 255, 1, map[uint8]uint8{0: 0, 64: 64, 128: 128, 255: 255}},
+		{"black and white points", 16, 235, 1, map[uint8]uint8{0: 0, 16: 0, 126: 128, 235: 255, 250: 255}},
+		{"darken the midtones", 0, 255, 2.2, map[uint8]uint8{0: 0, 128: 56, 255: 255}},
+		{"lighten the midtones", 0, 255, 0.5, map[uint8]uint8{0: 0, 64: 128, 255: 255}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := applyFilter(Levels(tc.black, tc.white, tc.gamma), testGradient())
+			for in, want := range tc.want {
+				if got := dst.GrayAt(int(in), 0).Y; absDiff(got, want) > 1 {
+					t.Errorf("%d: got %d, want %d", in, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoGamma(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		in      uint8
+		want    uint8
+		message string
+	}{
+		{"washed out", 0xb0, 0x80, "the midtones are brought to the middle gray"},
+		{"dark", 0x70, 0x80, "the midtones are brought to the middle gray"},
+		{"very dark", 0x30, 0x43, "the gamma is limited"},
+		{"very light", 0xf0, 0xdf, "the gamma is limited"},
+		{"blank", 0xff, 0xff, "nothing to correct"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src := testPage(16, 16, tc.in, 0)
+			// black is left untouched
+			src.SetGray(0, 0, color.Gray{})
+			dst := applyFilter(AutoGamma(0, 255), src)
+			if got := dst.GrayAt(8, 8).Y; absDiff(got, tc.want) > 2 {
+				t.Errorf("%s: got %#x, want %#x", tc.message, got, tc.want)
+			}
+			if got := dst.GrayAt(0, 0).Y; got != 0 {
+				t.Errorf("black: got %#x", got)
+			}
+		})
+	}
+}
+
+func TestDenoise(t *testing.T) {
+	for strength := 1; strength <= 3; strength++ {
+		// a flat image is left unchanged
+		for _, v := range applyFilter(Denoise(strength), testPage(16, 16, 0xc0, 0)).Pix {
+			if v != 0xc0 {
+				t.Fatalf("strength %d: flat image changed to %#x", strength, v)
+			}
+		}
+
+		// the specks are removed, the lines are kept
+		src := testPage(32, 32, 0xff, 0, image.Rect(8, 0, 16, 32))
+		src.SetGray(24, 8, color.Gray{})
+		src.SetGray(4, 20, color.Gray{Y: 0x80})
+		dst := applyFilter(Denoise(strength), src)
+		if v := dst.GrayAt(24, 8).Y; v != 0xff {
+			t.Errorf("strength %d: black speck: got %#x", strength, v)
+		}
+		if v := dst.GrayAt(4, 20).Y; v != 0xff {
+			t.Errorf("strength %d: gray speck: got %#x", strength, v)
+		}
+		if v := dst.GrayAt(12, 16).Y; v != 0 {
+			t.Errorf("strength %d: line: got %#x", strength, v)
+		}
+	}
+}
+
+func TestSharpen(t *testing.T) {
+	// a flat image is left unchanged
+	for _, v := range applyFilter(Sharpen(100), testPage(16, 16, 0x80, 0)).Pix {
+		if absDiff(v, 0x80) > 1 {
+			t.Fatalf("flat image changed to %#x", v)
+		}
+	}
+
+	// the edge between 2 grays gets more contrast, stronger with the strength
+	src := testPage(16, 16, 0x60, 0xa0, image.Rect(8, 0, 16, 16))
+	contrast := 0x40
+	for _, strength := range []int{25, 50, 100} {
+		dst := applyFilter(Sharpen(strength), src)
+		dark, light := dst.GrayAt(7, 8).Y, dst.GrayAt(8, 8).Y
+		if dark >= 0x60 || light <= 0xa0 || int(light)-int(dark) <= contrast {
+			t.Errorf("strength %d: edge %#x/%#x", strength, dark, light)
+		}
+		contrast = int(light) - int(dark)
+		// far from the edge, the grays are kept
+		if v := dst.GrayAt(1, 8).Y; absDiff(v, 0x60) > 1 {
+			t.Errorf("strength %d: dark side changed to %#x", strength, v)
+		}
+	}
+}
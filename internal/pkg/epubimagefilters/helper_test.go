@@ -0,0 +1,56 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/gift"
+)
+
+// gray image of the bounds, the luminance of each pixel is given by the function
+func testImage(bounds image.Rectangle, lum func(x, y int) uint8) *image.Gray {
+	img := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.SetGray(x, y, color.Gray{Y: lum(x, y)})
+		}
+	}
+	return img
+}
+
+// art without any uniform row or column
+func art(x, y int) uint8 {
+	return uint8((x*7 + y*13) % 256)
+}
+
+// page of the size filled with the background, with the rectangles of ink
+func testPage(w, h int, background, ink uint8, rects ...image.Rectangle) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Rect, image.NewUniform(color.Gray{Y: background}), image.Point{}, draw.Src)
+	for _, r := range rects {
+		draw.Draw(img, r, image.NewUniform(color.Gray{Y: ink}), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+// horizontal gradient from black to white, one column by level
+func testGradient() *image.Gray {
+	return testImage(image.Rect(0, 0, 256, 4), func(x, _ int) uint8 {
+		return uint8(x)
+	})
+}
+
+func applyFilter(f gift.Filter, src image.Image) *image.Gray {
+	g := gift.New(f)
+	dst := image.NewGray(g.Bounds(src.Bounds()))
+	g.Draw(dst, src)
+	return dst
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,89 @@
+package epubimagefilters
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/gift"
+)
+
+// range of the gamma picked from the histogram
+const (
+	autoGammaMin = 0.8
+	autoGammaMax = 2.2
+)
+
+// Levels Remap the black and white points, then apply the gamma correction.
+//
+// The luminance between the black and white points is stretched from 0 to 1, then raised to the power of the gamma:
+// a gamma > 1 darken the midtones, < 1 lighten them.
+func Levels(black, white int, gamma float64) gift.Filter {
+	return levels{black, white, gamma, false}
+}
+
+// AutoGamma Remap the black and white points, then apply a gamma picked from the histogram.
+//
+// The gamma brings the average of the midtones to the middle gray, washed out scans get darker.
+func AutoGamma(black, white int) gift.Filter {
+	return levels{black, white, 1, true}
+}
+
+type levels struct {
+	black, white int
+	gamma        float64
+	auto         bool
+}
+
+// position of the value between the black and white points
+func (f levels) stretch(v float64) float64 {
+	return min(1, max(0, (v*255-float64(f.black))/float64(f.white-f.black)))
+}
+
+// gamma bringing the average of the midtones, the values not close to black or white, to the middle gray
+func (f levels) autoGamma(src image.Image) float64 {
+	var sum float64
+	var count int
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := f.stretch(float64(color.GrayModel.Convert(src.At(x, y)).(color.Gray).Y) / 255)
+			if v > 0.05 && v < 0.95 {
+				sum += v
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 1
+	}
+	return min(autoGammaMax, max(autoGammaMin, math.Log(0.5)/math.Log(sum/float64(count))))
+}
+
+// Draw into the dst after applying the filter
+func (f levels) Draw(dst draw.Image, src image.Image, options *gift.Options) {
+	gamma := f.gamma
+	if f.auto {
+		gamma = f.autoGamma(src)
+	}
+
+	// the channels have 8 bits in the sources
+	var lut [256]float32
+	for i := range lut {
+		lut[i] = float32(math.Pow(f.stretch(float64(i)/255), gamma))
+	}
+	curve := func(v float32) float32 {
+		return lut[int(min(1, max(0, v))*255+0.5)]
+	}
+
+	gift.ColorFunc(func(r0, g0, b0, a0 float32) (r float32, g float32, b float32, a float32) {
+		return curve(r0), curve(g0), curve(b0), a0
+	}).Draw(dst, src, options)
+}
+
+// Bounds calculates the appropriate bounds of an image after applying the filter.
+func (f levels) Bounds(srcBounds image.Rectangle) (dstBounds image.Rectangle) {
+	dstBounds = srcBounds
+	return
+}
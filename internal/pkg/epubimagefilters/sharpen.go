@@ -0,0 +1,19 @@
+package epubimagefilters
+
+import (
+	"github.com/disintegration/gift"
+)
+
+// Sharpen Unsharp mask restoring the line art softened by the resize.
+//
+// The strength is between 1 and 100, 100 darken and lighten the edges by 150%.
+func Sharpen(strength int) gift.Filter {
+	return gift.UnsharpMask(1, 1.5*float32(strength)/100, 0.01)
+}
+
+// Denoise Median filter removing the specks, the JPEG noise and the texture of the paper.
+//
+// The strength is between 1 and 3, the neighborhood of each pixel is a disk of 3, 5 or 7 pixels.
+func Denoise(strength int) gift.Filter {
+	return gift.Median(2*strength+1, true)
+}
@@ -0,0 +1,232 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/sortpath"
+)
+
+// extensions of the files counted by the page index of the ComicInfo.xml, even if they are skipped
+var comicInfoImageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true,
+	".tif": true, ".tiff": true, ".avif": true, ".heic": true, ".jxl": true,
+}
+
+// pages of the ComicInfo.xml mapped to the images of the input
+type comicInfoPages struct {
+	mut sync.Mutex
+	// sorted names of the images, the position is the id given by the loader
+	sources []string
+	// new id of each image once the deleted ones are dropped, -1 if deleted
+	ids []int
+	// annotations by new id
+	pages    map[int]comicinfo.Page
+	coverId  int
+	hasCover bool
+}
+
+// keep the sorted names of the images of the input, the id of an image is its position
+func (e EPUBImageProcessor) setSources(names []string) {
+	var sources []string
+	for _, name := range names {
+		sources = append(sources, filepath.Clean(name))
+	}
+	e.pages.mut.Lock()
+	defer e.pages.mut.Unlock()
+	e.pages.sources = sources
+}
+
+// map the pages of the ComicInfo.xml to the images.
+//
+// the index of a page is the position of the file in the sorted list of the images of the input, the skipped files
+// with an image extension are counted. The images are renumbered without the deleted ones.
+// Without the list, the index is the id of the image.
+func (e EPUBImageProcessor) indexPages(imageCount int) {
+	e.pages.mut.Lock()
+	defer e.pages.mut.Unlock()
+
+	sourceIds := make([]int, 0, imageCount)
+	if e.pages.sources == nil {
+		for id := range imageCount {
+			sourceIds = append(sourceIds, id)
+		}
+	} else {
+		// the same name can come from 2 nested archives
+		ids := make(map[string][]int, len(e.pages.sources))
+		names := make([]string, 0, len(e.pages.sources))
+		for id, name := range e.pages.sources {
+			ids[name] = append(ids[name], id)
+			names = append(names, name)
+		}
+		for _, f := range e.Skipped() {
+			name := filepath.Join(f.Path, f.Name)
+			if comicInfoImageExtensions[strings.ToLower(filepath.Ext(name))] {
+				names = append(names, name)
+			}
+		}
+		sort.Sort(sortpath.By(names, e.SortPathMode))
+		for _, name := range names {
+			id := -1
+			if len(ids[name]) > 0 {
+				id, ids[name] = ids[name][0], ids[name][1:]
+			}
+			sourceIds = append(sourceIds, id)
+		}
+	}
+
+	annotations := map[int]comicinfo.Page{}
+	for _, p := range e.ComicInfo.Pages {
+		if p.Image < 0 || p.Image >= len(sourceIds) || sourceIds[p.Image] < 0 {
+			continue
+		}
+		annotations[sourceIds[p.Image]] = p
+	}
+
+	e.pages.ids = make([]int, imageCount)
+	e.pages.pages = map[int]comicinfo.Page{}
+	e.pages.coverId, e.pages.hasCover = 0, false
+	newId := 0
+	for id := range imageCount {
+		p, ok := annotations[id]
+		if ok && p.HasType("Deleted") {
+			e.pages.ids[id] = -1
+			continue
+		}
+		e.pages.ids[id] = newId
+		if ok {
+			e.pages.pages[newId] = p
+			if p.HasType("FrontCover") && !e.pages.hasCover {
+				e.pages.coverId, e.pages.hasCover = newId, true
+			}
+		}
+		newId++
+	}
+}
+
+// depth of a file in the input, the ComicInfo.xml closest to the root is used
+func (e EPUBImageProcessor) depth(name string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(name)), "/")
+}
+
+// ReadComicInfo read the ComicInfo.xml of the input.
+//
+// Nested archives are not read, and an empty ComicInfo is returned if none is found.
+func (e EPUBImageProcessor) ReadComicInfo() (comicinfo.ComicInfo, error) {
+	var (
+		found     bool
+		bestDepth int
+		data      []byte
+	)
+	candidate := func(name string, read func() ([]byte, error)) error {
+		if !comicinfo.IsComicInfo(name) || (found && e.depth(name) >= bestDepth) {
+			return nil
+		}
+		b, err := read()
+		if err != nil {
+			return err
+		}
+		found, bestDepth, data = true, e.depth(name), b
+		return nil
+	}
+
+	fi, err := os.Stat(e.Input)
+	if err != nil {
+		return comicinfo.ComicInfo{}, err
+	}
+
+	input := filepath.Clean(e.Input)
+	switch ext := strings.ToLower(filepath.Ext(input)); {
+	case fi.IsDir():
+		err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return candidate(path[len(input)+1:], func() ([]byte, error) {
+				return os.ReadFile(path)
+			})
+		})
+	case e.isTarball(input):
+		r, closeTar, terr := e.openTar()
+		if terr != nil {
+			return comicinfo.ComicInfo{}, terr
+		}
+		defer func() {
+			_ = closeTar()
+		}()
+		for {
+			h, terr := r.Next()
+			if terr == io.EOF {
+				break
+			}
+			if terr != nil {
+				return comicinfo.ComicInfo{}, terr
+			}
+			if err = candidate(h.Name, func() ([]byte, error) { return io.ReadAll(r) }); err != nil {
+				break
+			}
+		}
+	case ext == ".cbz" || ext == ".zip":
+		r, zerr := zip.OpenReader(input)
+		if zerr != nil {
+			return comicinfo.ComicInfo{}, zerr
+		}
+		defer func(r *zip.ReadCloser) {
+			_ = r.Close()
+		}(r)
+		for _, f := range r.File {
+			if err = candidate(f.Name, func() ([]byte, error) { return e.readAll(e.zipOpener(f)) }); err != nil {
+				break
+			}
+		}
+	case ext == ".cb7" || ext == ".7z":
+		r, zerr := sevenzip.OpenReaderWithPassword(input, e.Password)
+		if zerr != nil {
+			return comicinfo.ComicInfo{}, zerr
+		}
+		defer func(r *sevenzip.ReadCloser) {
+			_ = r.Close()
+		}(r)
+		for _, f := range r.File {
+			if err = candidate(f.Name, func() ([]byte, error) { return e.readAll(f.Open) }); err != nil {
+				break
+			}
+		}
+	case ext == ".cbr" || ext == ".rar":
+		r, rerr := rardecode.OpenReader(input, e.rarOptions()...)
+		if rerr != nil {
+			return comicinfo.ComicInfo{}, rerr
+		}
+		defer func(r *rardecode.ReadCloser) {
+			_ = r.Close()
+		}(r)
+		for {
+			h, rerr := r.Next()
+			if rerr == io.EOF {
+				break
+			}
+			if rerr != nil {
+				return comicinfo.ComicInfo{}, rerr
+			}
+			if err = candidate(h.Name, func() ([]byte, error) { return io.ReadAll(r) }); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil || !found {
+		return comicinfo.ComicInfo{}, err
+	}
+	return comicinfo.Parse(bytes.NewReader(data))
+}
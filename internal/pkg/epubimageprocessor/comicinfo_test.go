@@ -0,0 +1,108 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+func TestIndexPages(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		sources []string
+		skipped []string
+		pages   []comicinfo.Page
+		wantIds []int
+		// bookmark of the annotated pages by new id
+		wantPages map[int]string
+		// new id of the cover, -1 without cover
+		wantCover int
+	}{
+		{
+			name: "without the list of the images",
+			pages: []comicinfo.Page{
+				{Image: 1, Type: "Deleted"},
+				{Image: 2, Type: "FrontCover", Bookmark: "cover"},
+			},
+			wantIds:   []int{0, -1, 1, 2},
+			wantPages: map[int]string{1: "cover"},
+			wantCover: 1,
+		},
+		{
+			name:    "skipped images are counted",
+			sources: []string{"book/01.jpg", "book/02.jpg", "book/04.jpg", "book/05.jpg"},
+			skipped: []string{"book/03.png", "book/notes.txt"},
+			pages: []comicinfo.Page{
+				{Image: 0, Type: "Deleted"},
+				{Image: 2, Type: "Deleted"},
+				{Image: 3, Type: "FrontCover", Bookmark: "cover"},
+				{Image: 4, Bookmark: "chapter 1"},
+			},
+			wantIds:   []int{-1, 0, 1, 2},
+			wantPages: map[int]string{1: "cover", 2: "chapter 1"},
+			wantCover: 1,
+		},
+		{
+			name:    "same name from 2 nested archives",
+			sources: []string{"01.jpg", "01.jpg", "02.jpg", "03.jpg"},
+			pages: []comicinfo.Page{
+				{Image: 1, Bookmark: "second"},
+				{Image: 2, Type: "Deleted"},
+			},
+			wantIds:   []int{0, 1, -1, 2},
+			wantPages: map[int]string{1: "second"},
+			wantCover: -1,
+		},
+		{
+			name: "out of range",
+			pages: []comicinfo.Page{
+				{Image: -1, Type: "Deleted"},
+				{Image: 4, Type: "Deleted"},
+			},
+			wantIds:   []int{0, 1, 2, 3},
+			wantPages: map[int]string{},
+			wantCover: -1,
+		},
+		{
+			name: "first cover is used",
+			pages: []comicinfo.Page{
+				{Image: 0, Type: "Story"},
+				{Image: 2, Type: "FrontCover"},
+				{Image: 3, Type: "FrontCover", Bookmark: "back"},
+			},
+			wantIds:   []int{0, 1, 2, 3},
+			wantPages: map[int]string{0: "", 2: "", 3: "back"},
+			wantCover: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New(epuboptions.EPUBOptions{ComicInfo: comicinfo.ComicInfo{Pages: tc.pages}})
+			e.setSources(tc.sources)
+			for _, name := range tc.skipped {
+				e.skip(name, errors.New("skipped"))
+			}
+			e.indexPages(4)
+
+			if !reflect.DeepEqual(e.pages.ids, tc.wantIds) {
+				t.Errorf("ids: got %v, want %v", e.pages.ids, tc.wantIds)
+			}
+			pages := map[int]string{}
+			for id, p := range e.pages.pages {
+				pages[id] = p.Bookmark
+			}
+			if !reflect.DeepEqual(pages, tc.wantPages) {
+				t.Errorf("pages: got %v, want %v", pages, tc.wantPages)
+			}
+			cover := -1
+			if e.pages.hasCover {
+				cover = e.pages.coverId
+			}
+			if cover != tc.wantCover {
+				t.Errorf("cover: got %d, want %d", cover, tc.wantCover)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package epubimageprocessor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	_ "github.com/gen2brain/avif"
+	"github.com/gen2brain/heic"
+	_ "github.com/gen2brain/jpegxl"
+)
+
+var (
+	errUnsupportedImageFormat = errors.New("unsupported image format")
+	errNotAnImage             = errors.New("not an image")
+	errNestedArchive          = errors.New("nested archive")
+)
+
+type sourceImageFormat struct {
+	Format    string
+	Decodable bool
+	Magics    []string
+}
+
+// source image formats detected by content.
+//
+// decodable formats register themselves with image.RegisterFormat,
+// the others are kept to fail the conversion instead of silently dropping the page.
+//
+// magic use "?" as a wildcard, like image.RegisterFormat.
+var sourceImageFormats = []sourceImageFormat{
+	{"jpeg", true, []string{"\xff\xd8\xff"}},
+	{"png", true, []string{"\x89PNG\r\n\x1a\n"}},
+	{"webp", true, []string{"RIFF????WEBPVP8"}},
+	{"tiff", true, []string{"II*\x00", "MM\x00*"}},
+	{"gif", true, []string{"GIF87a", "GIF89a"}},
+	{"bmp", true, []string{"BM????\x00\x00\x00\x00"}},
+	{"avif", true, []string{"????ftypavif", "????ftypavis"}},
+	{"heic", true, []string{"????ftypheic", "????ftypheix", "????ftyphevc", "????ftyphevx", "????ftypheim", "????ftypheis"}},
+	{"jxl", true, []string{"\xff\x0a", "\x00\x00\x00\x0cJXL \r\n\x87\n"}},
+}
+
+func init() {
+	// heic only register the main brand, heif files may use the other ones
+	for _, brand := range []string{"heix", "hevc", "hevx", "heim", "heis"} {
+		image.RegisterFormat("heic", "????ftyp"+brand, heic.Decode, heic.DecodeConfig)
+	}
+}
+
+// match magic with wildcard
+func (e EPUBImageProcessor) matchMagic(magic string, head []byte) bool {
+	if len(head) < len(magic) {
+		return false
+	}
+	for i, c := range []byte(magic) {
+		if c != '?' && head[i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup the format with the first bytes of the file
+func (e EPUBImageProcessor) formatByContent(head []byte) (sourceImageFormat, bool) {
+	for _, f := range sourceImageFormats {
+		for _, magic := range f.Magics {
+			if e.matchMagic(magic, head) {
+				return f, true
+			}
+		}
+	}
+	return sourceImageFormat{}, false
+}
+
+// read the first bytes of the file, enough to detect the format of the images and the archives
+func (e EPUBImageProcessor) readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return head[:n], err
+}
+
+// check the first bytes of a file are from an image, errNestedArchive is returned for an archive
+func (e EPUBImageProcessor) sniffImageHead(head []byte) error {
+	if len(head) == 0 {
+		return fmt.Errorf("%w: empty file", errNotAnImage)
+	}
+	f, ok := e.formatByContent(head)
+	if ok && !f.Decodable {
+		return fmt.Errorf("%w: %s", errUnsupportedImageFormat, f.Format)
+	}
+	if !ok {
+		if format := e.archiveFormat("", head); format != "" {
+			return fmt.Errorf("%w: %s", errNestedArchive, format)
+		}
+		return fmt.Errorf("%w: %s", errNotAnImage, http.DetectContentType(head))
+	}
+	return nil
+}
+
+// check the content of the file is an image, only the beginning of the file is read.
+func (e EPUBImageProcessor) sniffImage(open func() (io.ReadCloser, error)) error {
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	defer func(f io.ReadCloser) {
+		_ = f.Close()
+	}(f)
+
+	head, err := e.readHead(f)
+	if err != nil {
+		return err
+	}
+	return e.sniffImageHead(head)
+}
+
+// decode an image, report the format if no decoder is available
+func (e EPUBImageProcessor) decodeImage(r io.Reader) (image.Image, error) {
+	head, err := e.readHead(r)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := e.formatByContent(head); ok && !f.Decodable {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedImageFormat, f.Format)
+	}
+	img, _, err := image.Decode(io.MultiReader(bytes.NewReader(head), r))
+	return img, err
+}
@@ -0,0 +1,112 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+func TestSniffImageHead(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{})
+	for _, tc := range []struct {
+		name string
+		head string
+		err  error
+	}{
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF", nil},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", nil},
+		{"gif", "GIF89a\x01\x00\x01\x00", nil},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", nil},
+		{"tiff", "II*\x00\x08\x00\x00\x00", nil},
+		{"bmp", "BM\x36\x00\x00\x00\x00\x00\x00\x00\x36\x00", nil},
+		{"avif", "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00", nil},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", nil},
+		{"jxl codestream", "\xff\x0a\xfa\x7f", nil},
+		{"jxl container", "\x00\x00\x00\x0cJXL \r\n\x87\n", nil},
+		{"zip", "PK\x03\x04\x14\x00", errNestedArchive},
+		{"rar", "Rar!\x1a\x07\x01\x00", errNestedArchive},
+		{"7z", "7z\xbc\xaf\x27\x1c\x00\x04", errNestedArchive},
+		{"gzip", "\x1f\x8b\x08\x00", errNestedArchive},
+		{"tar", strings.Repeat("\x00", 257) + "ustar\x0000", errNestedArchive},
+		{"text", "<?xml version=\"1.0\"?>", errNotAnImage},
+		{"empty", "", errNotAnImage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := e.sniffImageHead([]byte(tc.head)); !errors.Is(err, tc.err) {
+				t.Errorf("got %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+// lossless page of encodeImage
+func TestDecodeImageJXL(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "page.jxl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	img, err := New(epuboptions.EPUBOptions{}).decodeImage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := img.Bounds(), image.Rect(0, 0, 8, 12); got != want {
+		t.Errorf("bounds: got %v, want %v", got, want)
+	}
+	if got := color.GrayModel.Convert(img.At(2, 3)).(color.Gray).Y; got != 0x80 {
+		t.Errorf("pixel: got %#x, want 0x80", got)
+	}
+	if got := color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y; got != 0 {
+		t.Errorf("background: got %#x, want 0", got)
+	}
+}
+
+// the images are found by their content, whatever their extension
+func TestLoadSniffing(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "book.cbz")
+	err := os.WriteFile(input, zipBytes(t,
+		testFile{"01.jpg", encodeImage(t, "png")},
+		testFile{"02.txt", encodeImage(t, "jpeg")},
+		testFile{"03.png", []byte("not an image")},
+		testFile{"04", encodeImage(t, "png")},
+		testFile{"ComicInfo.xml", []byte("<ComicInfo/>")},
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(epuboptions.EPUBOptions{Input: input, Workers: 1})
+	total, output, err := e.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for task := range output {
+		if task.Error != nil {
+			t.Errorf("%s: %v", task.Name, task.Error)
+		}
+		if task.Image == nil || task.Image.Bounds().Dx() != 8 {
+			t.Errorf("%s: image not decoded", task.Name)
+		}
+		names = append(names, task.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"01.jpg", "02.txt", "04"}; total != len(want) || strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("images: got %d %v, want %v", total, names, want)
+	}
+
+	// the ComicInfo.xml is expected and not reported
+	skipped := e.Skipped()
+	if len(skipped) != 1 || skipped[0].Name != "03.png" || !errors.Is(skipped[0].Reason, errNotAnImage) {
+		t.Errorf("skipped: got %+v", skipped)
+	}
+}
@@ -0,0 +1,400 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/beevik/etree"
+	"github.com/disintegration/gift"
+)
+
+var errInvalidEPUB = errors.New("invalid epub")
+
+// an epub read as a source
+type epubSource struct {
+	files map[string]*zip.File
+}
+
+// read a file of the epub as xml
+func (s epubSource) readXML(name string) (*etree.Document, error) {
+	f, ok := s.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", errInvalidEPUB, name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(r io.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	doc := etree.NewDocument()
+	doc.ReadSettings.Permissive = true
+	if _, err = doc.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// resolve a link relative to the file that contains it, without the fragment
+func (s epubSource) resolve(base, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if u, err := url.PathUnescape(href); err == nil {
+		href = u
+	}
+	return path.Join(path.Dir(base), href)
+}
+
+// images referenced by a page, in the order of the document
+func (s epubSource) pageImages(page string) []string {
+	doc, err := s.readXML(page)
+	if err != nil {
+		return nil
+	}
+	images := make([]string, 0)
+	for _, elm := range doc.FindElements("//*") {
+		var src string
+		switch elm.Tag {
+		case "img":
+			src = elm.SelectAttrValue("src", "")
+		case "image":
+			src = elm.SelectAttrValue("xlink:href", elm.SelectAttrValue("href", ""))
+		}
+		if src != "" {
+			images = append(images, s.resolve(page, src))
+		}
+	}
+	return images
+}
+
+// a toc entry, the path is the list of titles from the root
+type epubTocEntry struct {
+	Page string
+	Path []string
+}
+
+// read the nav toc of an epub 3
+func (s epubSource) navToc(nav string) []epubTocEntry {
+	doc, err := s.readXML(nav)
+	if err != nil {
+		return nil
+	}
+
+	var root *etree.Element
+	for _, n := range doc.FindElements("//nav") {
+		if n.SelectAttrValue("epub:type", "") == "toc" {
+			root = n.SelectElement("ol")
+			break
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	entries := make([]epubTocEntry, 0)
+	var walk func(ol *etree.Element, parents []string)
+	walk = func(ol *etree.Element, parents []string) {
+		for _, li := range ol.SelectElements("li") {
+			title := parents
+			if a := li.SelectElement("a"); a != nil {
+				title = append(append([]string{}, parents...), strings.TrimSpace(a.Text()))
+				entries = append(entries, epubTocEntry{s.resolve(nav, a.SelectAttrValue("href", "")), title})
+			}
+			if child := li.SelectElement("ol"); child != nil {
+				walk(child, title)
+			}
+		}
+	}
+	walk(root, nil)
+	return entries
+}
+
+// read the ncx toc of an epub 2
+func (s epubSource) ncxToc(ncx string) []epubTocEntry {
+	doc, err := s.readXML(ncx)
+	if err != nil {
+		return nil
+	}
+
+	navMap := doc.FindElement("//navMap")
+	if navMap == nil {
+		return nil
+	}
+
+	entries := make([]epubTocEntry, 0)
+	var walk func(elm *etree.Element, parents []string)
+	walk = func(elm *etree.Element, parents []string) {
+		for _, np := range elm.SelectElements("navPoint") {
+			title := parents
+			label := np.FindElement("navLabel/text")
+			content := np.SelectElement("content")
+			if label != nil && content != nil {
+				title = append(append([]string{}, parents...), strings.TrimSpace(label.Text()))
+				entries = append(entries, epubTocEntry{s.resolve(ncx, content.SelectAttrValue("src", "")), title})
+			}
+			walk(np, title)
+		}
+	}
+	walk(navMap, nil)
+	return entries
+}
+
+// the part of an epub generated by this tool, from the title of its cover page "Cover 2 / 3"
+func (s epubSource) coverPart(coverPage string) int {
+	doc, err := s.readXML(coverPage)
+	if err != nil {
+		return 0
+	}
+	title := doc.FindElement("//title")
+	if title == nil {
+		return 0
+	}
+	var part, total int
+	if _, err = fmt.Sscanf(strings.TrimSpace(title.Text()), "Cover %d / %d", &part, &total); err != nil {
+		return 1
+	}
+	return part
+}
+
+// the images look the same, ignoring the compression and the levels of gray of the cover
+func (s epubSource) sameImage(a, b func() (io.ReadCloser, error)) bool {
+	thumbnail := func(open func() (io.ReadCloser, error)) (image.Point, *image.Gray) {
+		r, err := open()
+		if err != nil {
+			return image.Point{}, nil
+		}
+		defer func(r io.ReadCloser) {
+			_ = r.Close()
+		}(r)
+		img, _, err := image.Decode(r)
+		if err != nil {
+			return image.Point{}, nil
+		}
+		g := gift.New(gift.Grayscale(), gift.Resize(16, 16, gift.BoxResampling))
+		dst := image.NewGray(g.Bounds(img.Bounds()))
+		g.Draw(dst, img)
+		return img.Bounds().Size(), dst
+	}
+
+	sizeA, thumbA := thumbnail(a)
+	sizeB, thumbB := thumbnail(b)
+	if thumbA == nil || thumbB == nil || sizeA != sizeB {
+		return false
+	}
+	diff := 0
+	for i := range thumbA.Pix {
+		d := int(thumbA.Pix[i]) - int(thumbB.Pix[i])
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	return diff < 8*len(thumbA.Pix)
+}
+
+// list the images of the epub in the spine order, with the toc as directories.
+//
+// the cover is added first if no page display it.
+// the title page generated by this tool is skipped, like its cover when it is a copy of the first page
+// or the cover of another part.
+func (s epubSource) images() (images []nestedImage, err error) {
+	container, err := s.readXML("META-INF/container.xml")
+	if err != nil {
+		return
+	}
+	rootFile := container.FindElement("//rootfile")
+	if rootFile == nil {
+		return nil, fmt.Errorf("%w: missing rootfile", errInvalidEPUB)
+	}
+	opfPath := rootFile.SelectAttrValue("full-path", "")
+
+	opf, err := s.readXML(opfPath)
+	if err != nil {
+		return
+	}
+
+	type item struct {
+		Href       string
+		MediaType  string
+		Properties string
+	}
+	manifest := make(map[string]item)
+	var nav, coverImage string
+	for _, elm := range opf.FindElements("//manifest/item") {
+		it := item{
+			s.resolve(opfPath, elm.SelectAttrValue("href", "")),
+			elm.SelectAttrValue("media-type", ""),
+			elm.SelectAttrValue("properties", ""),
+		}
+		manifest[elm.SelectAttrValue("id", "")] = it
+		for _, p := range strings.Fields(it.Properties) {
+			switch p {
+			case "nav":
+				nav = it.Href
+			case "cover-image":
+				coverImage = it.Href
+			}
+		}
+	}
+	if coverImage == "" {
+		for _, elm := range opf.FindElements("//metadata/meta[@name='cover']") {
+			if it, ok := manifest[elm.SelectAttrValue("content", "")]; ok && strings.HasPrefix(it.MediaType, "image/") {
+				coverImage = it.Href
+			}
+		}
+	}
+
+	var toc []epubTocEntry
+	if nav != "" {
+		toc = s.navToc(nav)
+	} else if spine := opf.FindElement("//spine"); spine != nil {
+		if it, ok := manifest[spine.SelectAttrValue("toc", "")]; ok {
+			toc = s.ncxToc(it.Href)
+		}
+	}
+	// keep the deepest entry when multiple entries share the same page
+	tocByPage := make(map[string][]string)
+	for _, t := range toc {
+		tocByPage[t.Page] = t.Path
+	}
+
+	seen := make(map[string]bool)
+	var currentPath []string
+	addImage := func(href string) {
+		if seen[href] {
+			return
+		}
+		f, ok := s.files[href]
+		if !ok {
+			return
+		}
+		seen[href] = true
+		images = append(images, nestedImage{
+			path.Join(append(append([]string{}, currentPath...), path.Base(href))...),
+			f.Open,
+		})
+	}
+
+	for _, elm := range opf.FindElements("//spine/itemref") {
+		idref := elm.SelectAttrValue("idref", "")
+		it, ok := manifest[idref]
+		if !ok || idref == "page_title" {
+			continue
+		}
+		if p, ok := tocByPage[it.Href]; ok {
+			currentPath = make([]string, 0, len(p))
+			for _, t := range p {
+				currentPath = append(currentPath, strings.ReplaceAll(t, "/", "-"))
+			}
+		}
+		if strings.HasPrefix(it.MediaType, "image/") {
+			addImage(it.Href)
+			continue
+		}
+		for _, img := range s.pageImages(it.Href) {
+			addImage(img)
+		}
+	}
+
+	if coverImage == "" || seen[coverImage] {
+		return
+	}
+	f, ok := s.files[coverImage]
+	if !ok {
+		return
+	}
+	if coverImage == manifest["img_cover"].Href && len(images) > 0 {
+		if s.coverPart(manifest["page_cover"].Href) > 1 || s.sameImage(f.Open, images[0].Open) {
+			return
+		}
+	}
+	images = append([]nestedImage{{path.Base(coverImage), f.Open}}, images...)
+
+	return
+}
+
+// load an epub, images are kept in the order of the spine
+func (e EPUBImageProcessor) loadEpub() (totalImages int, output chan task, err error) {
+	r, err := zip.OpenReader(e.Input)
+	if err != nil {
+		return
+	}
+
+	s := epubSource{make(map[string]*zip.File)}
+	for _, f := range r.File {
+		s.files[f.Name] = f
+	}
+
+	images, err := s.images()
+	if err != nil {
+		_ = r.Close()
+		return
+	}
+
+	totalImages = len(images)
+	if totalImages == 0 {
+		_ = r.Close()
+		err = errNoImagesFound
+		return
+	}
+
+	type job struct {
+		Id  int
+		Img nestedImage
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i, img := range images {
+			jobs <- job{i, img}
+		}
+	}()
+
+	output = make(chan task, e.Workers)
+	wg := &sync.WaitGroup{}
+	for range e.WorkersRatio(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var img image.Image
+				var err error
+				if e.decode() {
+					var f io.ReadCloser
+					f, err = job.Img.Open()
+					if err == nil {
+						img, err = e.decodeImage(f)
+						_ = f.Close()
+					}
+				}
+
+				p, fn := path.Split(job.Img.Name)
+				if err != nil {
+					img = e.corruptedImage(p, fn)
+				}
+				output <- task{
+					Id:    job.Id,
+					Image: img,
+					Path:  p,
+					Name:  fn,
+					Error: err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(output)
+		_ = r.Close()
+	}()
+	return
+}
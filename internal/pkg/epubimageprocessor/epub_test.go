@@ -0,0 +1,186 @@
+package epubimageprocessor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+// epub with the package in OEBPS/content.opf
+func epubBytes(t *testing.T, opf string, files ...testFile) []byte {
+	t.Helper()
+	return zipBytes(t, append([]testFile{
+		{"mimetype", []byte("application/epub+zip")},
+		{"META-INF/container.xml", []byte(`<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`)},
+		{"OEBPS/content.opf", []byte(`<?xml version="1.0"?>
+<package version="3.0" xmlns="http://www.idpf.org/2007/opf">` + opf + `</package>`)},
+	}, files...)...)
+}
+
+// xhtml page displaying the images
+func xhtmlPage(title string, images ...string) []byte {
+	var body strings.Builder
+	for _, img := range images {
+		fmt.Fprintf(&body, `<img src="%s"/>`, img)
+	}
+	return []byte(`<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>` + title + `</title></head><body>` + body.String() + `</body></html>`)
+}
+
+func TestLoadEpub(t *testing.T) {
+	page := grayPage(t, 0x80)
+	other := grayPage(t, 0x20)
+
+	// pages and images of the epub generated by this tool
+	generated := func(coverTitle string, cover []byte) []testFile {
+		return []testFile{
+			{"OEBPS/Text/cover.xhtml", xhtmlPage(coverTitle, "../Images/cover.png")},
+			{"OEBPS/Images/cover.png", cover},
+			{"OEBPS/Text/title.xhtml", xhtmlPage("Book", "../Images/title.png")},
+			{"OEBPS/Images/title.png", other},
+			{"OEBPS/Text/1.xhtml", xhtmlPage("1", "../Images/1.png")},
+			{"OEBPS/Images/1.png", page},
+			{"OEBPS/Text/2.xhtml", xhtmlPage("2", "../Images/2.png")},
+			{"OEBPS/Images/2.png", other},
+		}
+	}
+	const generatedOpf = `
+<metadata><meta name="cover" content="img_cover"/></metadata>
+<manifest>
+  <item id="page_cover" href="Text/cover.xhtml" media-type="application/xhtml+xml"/>
+  <item id="img_cover" href="Images/cover.png" media-type="image/png"/>
+  <item id="page_title" href="Text/title.xhtml" media-type="application/xhtml+xml"/>
+  <item id="img_title" href="Images/title.png" media-type="image/png"/>
+  <item id="page_1" href="Text/1.xhtml" media-type="application/xhtml+xml"/>
+  <item id="img_1" href="Images/1.png" media-type="image/png"/>
+  <item id="page_2" href="Text/2.xhtml" media-type="application/xhtml+xml"/>
+  <item id="img_2" href="Images/2.png" media-type="image/png"/>
+</manifest>
+<spine><itemref idref="page_title"/><itemref idref="page_1"/><itemref idref="page_2"/></spine>`
+
+	for _, tc := range []struct {
+		name  string
+		opf   string
+		files []testFile
+		want  []string
+	}{
+		{
+			name: "nav toc",
+			opf: `
+<manifest>
+  <item id="nav" href="nav.xhtml" properties="nav" media-type="application/xhtml+xml"/>
+  <item id="cover" href="Images/cover.png" properties="cover-image" media-type="image/png"/>
+  <item id="a" href="Text/a.xhtml" media-type="application/xhtml+xml"/>
+  <item id="b" href="Text/b.xhtml" media-type="application/xhtml+xml"/>
+  <item id="c" href="Text/c.xhtml" media-type="application/xhtml+xml"/>
+  <item id="d" href="Images/p5.png" media-type="image/png"/>
+</manifest>
+<spine><itemref idref="c"/><itemref idref="a"/><itemref idref="b"/><itemref idref="d"/></spine>`,
+			files: []testFile{
+				{"OEBPS/nav.xhtml", []byte(`<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="landmarks"><ol><li><a href="Text/b.xhtml">Landmark</a></li></ol></nav>
+<nav epub:type="toc"><ol>
+  <li><a href="Text/c.xhtml">Prologue</a></li>
+  <li><a href="Text/a.xhtml">Chapter 1</a><ol><li><a href="Text/b.xhtml#top">Part 1/2</a></li></ol></li>
+</ol></nav>
+</body></html>`)},
+				{"OEBPS/Images/cover.png", other},
+				{"OEBPS/Text/a.xhtml", xhtmlPage("a", "../Images/p1.png")},
+				{"OEBPS/Text/b.xhtml", xhtmlPage("b", "../Images/p2.png", "../Images/p3.png", "../Images/p1.png")},
+				{"OEBPS/Text/c.xhtml", xhtmlPage("c", "../Images/p%204.png")},
+				{"OEBPS/Images/p1.png", page},
+				{"OEBPS/Images/p2.png", page},
+				{"OEBPS/Images/p3.png", page},
+				{"OEBPS/Images/p 4.png", page},
+				{"OEBPS/Images/p5.png", page},
+			},
+			want: []string{
+				"cover.png",
+				"Prologue/p 4.png",
+				"Chapter 1/p1.png",
+				"Chapter 1/Part 1-2/p2.png",
+				"Chapter 1/Part 1-2/p3.png",
+				"Chapter 1/Part 1-2/p5.png",
+			},
+		},
+		{
+			name: "ncx toc",
+			opf: `
+<metadata><meta name="cover" content="cover"/></metadata>
+<manifest>
+  <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+  <item id="cover" href="Images/p1.png" media-type="image/png"/>
+  <item id="a" href="Text/a.xhtml" media-type="application/xhtml+xml"/>
+  <item id="b" href="Text/b.xhtml" media-type="application/xhtml+xml"/>
+</manifest>
+<spine toc="ncx"><itemref idref="a"/><itemref idref="b"/></spine>`,
+			files: []testFile{
+				{"OEBPS/toc.ncx", []byte(`<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><navMap>
+  <navPoint id="1"><navLabel><text>Chapter 1</text></navLabel><content src="Text/a.xhtml"/>
+    <navPoint id="2"><navLabel><text>Scene</text></navLabel><content src="Text/b.xhtml"/></navPoint>
+  </navPoint>
+</navMap></ncx>`)},
+				{"OEBPS/Text/a.xhtml", xhtmlPage("a", "../Images/p1.png")},
+				{"OEBPS/Text/b.xhtml", xhtmlPage("b", "../Images/p2.png")},
+				{"OEBPS/Images/p1.png", page},
+				{"OEBPS/Images/p2.png", page},
+			},
+			// the cover is already displayed by a page
+			want: []string{"Chapter 1/p1.png", "Chapter 1/Scene/p2.png"},
+		},
+		{
+			name:  "generated cover copy of the first page",
+			opf:   generatedOpf,
+			files: generated("Cover", grayPage(t, 0x84)),
+			want:  []string{"1.png", "2.png"},
+		},
+		{
+			name:  "generated cover of the next part",
+			opf:   generatedOpf,
+			files: generated("Cover 2 / 3", grayPage(t, 0xf0)),
+			want:  []string{"1.png", "2.png"},
+		},
+		{
+			name:  "generated cover not repeated in the pages",
+			opf:   generatedOpf,
+			files: generated("Cover", grayPage(t, 0xf0)),
+			want:  []string{"cover.png", "1.png", "2.png"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "book.epub")
+			if err := os.WriteFile(input, epubBytes(t, tc.opf, tc.files...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			names := loadNames(t, New(epuboptions.EPUBOptions{Input: input, Workers: 2}))
+			got := make([]string, len(names))
+			for id, name := range names {
+				got[id] = name
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadEpubInvalid(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "book.epub")
+	if err := os.WriteFile(input, zipBytes(t, testFile{"mimetype", []byte("application/epub+zip")}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := New(epuboptions.EPUBOptions{Input: input, Workers: 1})
+	if _, _, err := e.load(); err == nil || !strings.Contains(err.Error(), "invalid epub") {
+		t.Errorf("got %v", err)
+	}
+}
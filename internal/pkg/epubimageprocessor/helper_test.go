@@ -0,0 +1,320 @@
+package epubimageprocessor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// file of a test input
+type testFile struct {
+	name string
+	data []byte
+}
+
+// small page encoded in the format
+func encodeImage(t *testing.T, format string) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 8, 12))
+	img.SetGray(2, 3, color.Gray{Y: 0x80})
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&b, img)
+	case "jpeg":
+		err = jpeg.Encode(&b, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// page of the size of encodeImage filled with the gray, encoded in png
+func grayPage(t *testing.T, y uint8) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 8, 12))
+	for i := range img.Pix {
+		img.Pix[i] = y
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// zip archive of the files
+func zipBytes(t *testing.T, files ...testFile) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, f := range files {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(f.data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// tar archive of the entries, the files are added as regular files
+func tarBytes(t *testing.T, entries ...any) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for _, entry := range entries {
+		var err error
+		switch entry := entry.(type) {
+		case testFile:
+			if err = w.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data))}); err == nil {
+				_, err = w.Write(entry.data)
+			}
+		case *tar.Header:
+			err = w.WriteHeader(entry)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// 7z archive of the files stored without compression.
+// A solid archive keeps all the files in one stream.
+// With a password, the streams and the header are encrypted with AES, like 7z -mhe=on.
+func sevenZipBytes(t *testing.T, solid bool, password string, files ...testFile) []byte {
+	t.Helper()
+	groups := make([][]testFile, 0, len(files))
+	if solid {
+		groups = append(groups, files)
+	} else {
+		for _, f := range files {
+			groups = append(groups, []testFile{f})
+		}
+	}
+
+	// the encrypted streams are padded to the block size, the padding ends in the last file
+	pad := func(b []byte) []byte {
+		if password == "" || len(b)%aes.BlockSize == 0 {
+			return b
+		}
+		return append(b, make([]byte, aes.BlockSize-len(b)%aes.BlockSize)...)
+	}
+	folder := func(h *bytes.Buffer) {
+		if password == "" {
+			h.Write([]byte{1, 0x01, 0x00}) // copy
+			return
+		}
+		// AES-256 + SHA-256 with 1 cycle, no salt and a 16 bytes IV
+		h.Write([]byte{1, 0x24, 0x06, 0xf1, 0x07, 0x01, 18, 0x40, 0x0f})
+		h.Write(sevenZipIV)
+	}
+
+	var packed, header bytes.Buffer
+	var packSizes []int
+	var unpacked [][]byte
+	for _, g := range groups {
+		var data []byte
+		for _, f := range g {
+			data = append(data, f.data...)
+		}
+		data = pad(data)
+		unpacked = append(unpacked, data)
+		enc := sevenZipEncrypt(password, data)
+		packSizes = append(packSizes, len(enc))
+		packed.Write(enc)
+	}
+
+	header.WriteByte(0x01) // header
+	header.WriteByte(0x04) // main streams
+	header.WriteByte(0x06) // pack info
+	writeSevenZipNumber(&header, 0)
+	writeSevenZipNumber(&header, uint64(len(groups)))
+	header.WriteByte(0x09)
+	for _, s := range packSizes {
+		writeSevenZipNumber(&header, uint64(s))
+	}
+	header.WriteByte(0x00)
+	header.WriteByte(0x07) // unpack info
+	header.WriteByte(0x0b)
+	writeSevenZipNumber(&header, uint64(len(groups)))
+	header.WriteByte(0x00)
+	for range groups {
+		folder(&header)
+	}
+	header.WriteByte(0x0c)
+	for _, data := range unpacked {
+		writeSevenZipNumber(&header, uint64(len(data)))
+	}
+	header.WriteByte(0x00)
+	header.WriteByte(0x08) // sub streams
+	header.WriteByte(0x0d)
+	for _, g := range groups {
+		writeSevenZipNumber(&header, uint64(len(g)))
+	}
+	header.WriteByte(0x09)
+	for _, g := range groups {
+		for _, f := range g[:len(g)-1] {
+			writeSevenZipNumber(&header, uint64(len(f.data)))
+		}
+	}
+	header.WriteByte(0x0a)
+	header.WriteByte(0x01)
+	for i, g := range groups {
+		offset := 0
+		for j, f := range g {
+			data := unpacked[i][offset : offset+len(f.data)]
+			if j == len(g)-1 {
+				data = unpacked[i][offset:]
+			}
+			_ = binary.Write(&header, binary.LittleEndian, crc32.ChecksumIEEE(data))
+			offset += len(f.data)
+		}
+	}
+	header.WriteByte(0x00)
+	header.WriteByte(0x00)
+	header.WriteByte(0x05) // files
+	writeSevenZipNumber(&header, uint64(len(files)))
+	var names bytes.Buffer
+	for _, f := range files {
+		for _, r := range f.name + "\x00" {
+			_ = binary.Write(&names, binary.LittleEndian, uint16(r))
+		}
+	}
+	header.WriteByte(0x11)
+	writeSevenZipNumber(&header, uint64(names.Len()+1))
+	header.WriteByte(0x00)
+	header.Write(names.Bytes())
+	header.WriteByte(0x00)
+	header.WriteByte(0x00)
+
+	next := header.Bytes()
+	if password != "" {
+		data := pad(next)
+		enc := sevenZipEncrypt(password, data)
+		var encoded bytes.Buffer
+		encoded.WriteByte(0x17) // encoded header
+		encoded.WriteByte(0x06)
+		writeSevenZipNumber(&encoded, uint64(packed.Len()))
+		writeSevenZipNumber(&encoded, 1)
+		encoded.WriteByte(0x09)
+		writeSevenZipNumber(&encoded, uint64(len(enc)))
+		encoded.WriteByte(0x00)
+		encoded.WriteByte(0x07)
+		encoded.WriteByte(0x0b)
+		writeSevenZipNumber(&encoded, 1)
+		encoded.WriteByte(0x00)
+		folder(&encoded)
+		encoded.WriteByte(0x0c)
+		writeSevenZipNumber(&encoded, uint64(len(data)))
+		encoded.WriteByte(0x00)
+		encoded.WriteByte(0x00)
+		packed.Write(enc)
+		next = encoded.Bytes()
+	}
+
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start, uint64(packed.Len()))
+	binary.LittleEndian.PutUint64(start[8:], uint64(len(next)))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(next))
+
+	var b bytes.Buffer
+	b.Write([]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0, 4})
+	_ = binary.Write(&b, binary.LittleEndian, crc32.ChecksumIEEE(start))
+	b.Write(start)
+	b.Write(packed.Bytes())
+	b.Write(next)
+	return b.Bytes()
+}
+
+var sevenZipIV = []byte("0123456789abcdef")
+
+func sevenZipEncrypt(password string, data []byte) []byte {
+	if password == "" {
+		return data
+	}
+	h := sha256.New()
+	for _, r := range password {
+		_ = binary.Write(h, binary.LittleEndian, uint16(r))
+	}
+	_ = binary.Write(h, binary.LittleEndian, uint64(0))
+	block, _ := aes.NewCipher(h.Sum(nil))
+	enc := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, sevenZipIV).CryptBlocks(enc, data)
+	return enc
+}
+
+// variable length number of the 7z headers
+func writeSevenZipNumber(b *bytes.Buffer, v uint64) {
+	for n := range 8 {
+		if v < 1<<(7*(n+1)) {
+			b.WriteByte(byte(0xff<<(8-n)) | byte(v>>(8*n)))
+			for i := range n {
+				b.WriteByte(byte(v >> (8 * i)))
+			}
+			return
+		}
+	}
+	b.WriteByte(0xff)
+	_ = binary.Write(b, binary.LittleEndian, v)
+}
+
+// strip of art without any gutter, except the white rows
+func testStrip(w, h int, gutters ...int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			v := uint8((x*7 + y*13) % 256)
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 0xff})
+		}
+	}
+	for _, y := range gutters {
+		for x := range w {
+			img.SetRGBA(x, y, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+		}
+	}
+	return img
+}
+
+// white page of the size with a black content
+func writeTestPage(t *testing.T, name string, w, h int, content image.Rectangle) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(img, content, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if err = png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
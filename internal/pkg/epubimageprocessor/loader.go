@@ -1,14 +1,14 @@
 package epubimageprocessor
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	_ "image/jpeg"
-	_ "image/png"
 	"io"
 	"io/fs"
 	"os"
@@ -18,18 +18,15 @@ import (
 	"sync"
 
 	"golang.org/x/image/font/gofont/gomonobold"
-	_ "golang.org/x/image/tiff"
-	_ "golang.org/x/image/webp"
 
+	"github.com/bodgit/sevenzip"
 	"github.com/fogleman/gg"
 	"github.com/golang/freetype/truetype"
+	"github.com/klauspost/compress/zstd"
 	"github.com/nwaples/rardecode/v2"
-	pdfimage "github.com/raff/pdfreader/image"
-	"github.com/raff/pdfreader/pdfread"
 
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/sortpath"
-
-	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
 )
 
 type task struct {
@@ -38,15 +35,116 @@ type task struct {
 	Path  string
 	Name  string
 	Error error
+	// crop box shared with the other pages of the book or the chapter
+	UniformCrop image.Rectangle
 }
 
 var errNoImagesFound = errors.New("no images found")
 
-// only accept jpg, png and webp as source file
-func (e EPUBImageProcessor) isSupportedImage(path string) bool {
-	switch strings.ToLower(filepath.Ext(path)) {
-	case ".jpg", ".jpeg", ".png", ".webp", ".tiff":
-		{
+// error reading the input while the images are streamed, the book can't be converted
+type inputError struct {
+	Name string
+	Err  error
+}
+
+func (e inputError) Error() string {
+	return fmt.Sprintf("error reading %s: %s", e.Name, e.Err)
+}
+
+func (e inputError) Unwrap() error {
+	return e.Err
+}
+
+// drop the tasks failing to read the input, the first error is returned once the input is consumed
+func (e EPUBImageProcessor) checkInput(input chan task) (chan task, func() error) {
+	output := make(chan task, e.Workers)
+	var failure error
+	go func() {
+		defer close(output)
+		for t := range input {
+			var ierr inputError
+			if errors.As(t.Error, &ierr) {
+				if failure == nil {
+					failure = ierr
+				}
+				continue
+			}
+			output <- t
+		}
+	}()
+	return output, func() error {
+		// the input is closed once consumed
+		for range output {
+		}
+		return failure
+	}
+}
+
+// SkippedFile file of the input that is not an image
+type SkippedFile struct {
+	Path   string
+	Name   string
+	Reason error
+}
+
+type skippedFiles struct {
+	mut   sync.Mutex
+	files []SkippedFile
+}
+
+// keep track of the skipped file with the reason, the metadata file is expected and not reported
+func (e EPUBImageProcessor) skip(name string, reason error) {
+	if comicinfo.IsComicInfo(name) {
+		return
+	}
+	p, fn := filepath.Split(filepath.Clean(name))
+	e.skipped.mut.Lock()
+	defer e.skipped.mut.Unlock()
+	e.skipped.files = append(e.skipped.files, SkippedFile{p, fn, reason})
+}
+
+// forget skipped files, when the input is listed again
+func (e EPUBImageProcessor) resetSkipped() {
+	e.skipped.mut.Lock()
+	defer e.skipped.mut.Unlock()
+	e.skipped.files = nil
+}
+
+// Skipped files of the input that are not images, sorted by path
+func (e EPUBImageProcessor) Skipped() []SkippedFile {
+	e.skipped.mut.Lock()
+	defer e.skipped.mut.Unlock()
+
+	names := make([]string, 0, len(e.skipped.files))
+	byName := make(map[string]SkippedFile)
+	for _, f := range e.skipped.files {
+		name := filepath.Join(f.Path, f.Name)
+		names = append(names, name)
+		byName[name] = f
+	}
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+
+	files := make([]SkippedFile, 0, len(names))
+	for _, name := range names {
+		files = append(files, byName[name])
+	}
+	return files
+}
+
+// pages in a format that can't be decoded, the book would miss them
+func (e EPUBImageProcessor) unsupportedPages() error {
+	for _, f := range e.Skipped() {
+		if errors.Is(f.Reason, errUnsupportedImageFormat) {
+			return fmt.Errorf("%s: %w", filepath.Join(f.Path, f.Name), f.Reason)
+		}
+	}
+	return nil
+}
+
+// tar archive can be compressed, the extension can't be taken alone
+func (e EPUBImageProcessor) isTarball(path string) bool {
+	for _, ext := range []string{".cbt", ".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst"} {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
 			return true
 		}
 	}
@@ -55,6 +153,9 @@ func (e EPUBImageProcessor) isSupportedImage(path string) bool {
 
 // Load images from input
 func (e EPUBImageProcessor) load() (totalImages int, output chan task, err error) {
+	// the loaders of the pdf and the epub keep the order of the pages, the ids follow the ComicInfo.xml
+	e.setSources(nil)
+
 	fi, err := os.Stat(e.Input)
 	if err != nil {
 		return
@@ -63,16 +164,22 @@ func (e EPUBImageProcessor) load() (totalImages int, output chan task, err error
 	// get all images though a channel of bytes
 	if fi.IsDir() {
 		return e.loadDir()
+	} else if e.isTarball(e.Input) {
+		return e.loadCbt()
 	} else {
 		switch ext := strings.ToLower(filepath.Ext(e.Input)); ext {
 		case ".cbz", ".zip":
 			return e.loadCbz()
 		case ".cbr", ".rar":
 			return e.loadCbr()
+		case ".cb7", ".7z":
+			return e.loadCb7()
 		case ".pdf":
 			return e.loadPdf()
+		case ".epub":
+			return e.loadEpub()
 		default:
-			err = fmt.Errorf("unknown file format (%s): support .cbz, .zip, .cbr, .rar, .pdf", ext)
+			err = fmt.Errorf("unknown file format (%s): support .cbz, .zip, .cbr, .rar, .cb7, .7z, .cbt, .tar, .tar.gz, .tar.zst, .pdf, .epub", ext)
 			return
 		}
 	}
@@ -104,15 +211,29 @@ func (e EPUBImageProcessor) corruptedImage(path, name string) image.Image {
 // load a directory of images
 func (e EPUBImageProcessor) loadDir() (totalImages int, output chan task, err error) {
 	images := make([]string, 0)
+	hasNestedArchive := false
 
 	input := filepath.Clean(e.Input)
 	err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() && e.isSupportedImage(path) {
-			images = append(images, path)
+		if d.IsDir() {
+			return nil
+		}
+		if e.isSupportedArchive(path) {
+			hasNestedArchive = true
+			return nil
+		}
+		if serr := e.sniffImage(func() (io.ReadCloser, error) { return os.Open(path) }); serr != nil {
+			if errors.Is(serr, errNestedArchive) {
+				hasNestedArchive = true
+				return nil
+			}
+			e.skip(path[len(input)+1:], serr)
+			return nil
 		}
+		images = append(images, path)
 		return nil
 	})
 
@@ -120,6 +241,10 @@ func (e EPUBImageProcessor) loadDir() (totalImages int, output chan task, err er
 		return
 	}
 
+	if hasNestedArchive {
+		return e.loadNested()
+	}
+
 	totalImages = len(images)
 
 	if totalImages == 0 {
@@ -128,6 +253,11 @@ func (e EPUBImageProcessor) loadDir() (totalImages int, output chan task, err er
 	}
 
 	sort.Sort(sortpath.By(images, e.SortPathMode))
+	names := make([]string, len(images))
+	for i, path := range images {
+		names[i] = path[len(input)+1:]
+	}
+	e.setSources(names)
 
 	// Queue all file with id
 	type job struct {
@@ -152,11 +282,11 @@ func (e EPUBImageProcessor) loadDir() (totalImages int, output chan task, err er
 			for job := range jobs {
 				var img image.Image
 				var err error
-				if !e.Dry {
+				if e.decode() {
 					var f *os.File
 					f, err = os.Open(job.Path)
 					if err == nil {
-						img, _, err = image.Decode(f)
+						img, err = e.decodeImage(f)
 						_ = f.Close()
 					}
 				}
@@ -198,10 +328,34 @@ func (e EPUBImageProcessor) loadCbz() (totalImages int, output chan task, err er
 	}
 
 	images := make([]*zip.File, 0)
+	hasNestedArchive := false
 	for _, f := range r.File {
-		if !f.FileInfo().IsDir() && e.isSupportedImage(f.Name) {
-			images = append(images, f)
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if e.isSupportedArchive(f.Name) {
+			hasNestedArchive = true
+			continue
+		}
+		if serr := e.sniffImage(e.zipOpener(f)); serr != nil {
+			if errors.Is(serr, errNestedArchive) {
+				hasNestedArchive = true
+				continue
+			}
+			if perr := e.passwordError(serr); perr != nil {
+				_ = r.Close()
+				err = perr
+				return
+			}
+			e.skip(f.Name, serr)
+			continue
 		}
+		images = append(images, f)
+	}
+
+	if hasNestedArchive {
+		_ = r.Close()
+		return e.loadNested()
 	}
 
 	totalImages = len(images)
@@ -217,6 +371,7 @@ func (e EPUBImageProcessor) loadCbz() (totalImages int, output chan task, err er
 		names = append(names, img.Name)
 	}
 	sort.Sort(sortpath.By(names, e.SortPathMode))
+	e.setSources(names)
 
 	indexedNames := make(map[string]int)
 	for i, name := range names {
@@ -244,13 +399,13 @@ func (e EPUBImageProcessor) loadCbz() (totalImages int, output chan task, err er
 			for job := range jobs {
 				var img image.Image
 				var err error
-				if !e.Dry {
+				if e.decode() {
 					var f io.ReadCloser
-					f, err = job.F.Open()
+					f, err = e.zipOpener(job.F)()
 					if err == nil {
-						img, _, err = image.Decode(f)
+						img, err = e.decodeImage(f)
+						_ = f.Close()
 					}
-					_ = f.Close()
 				}
 
 				p, fn := filepath.Split(filepath.Clean(job.F.Name))
@@ -279,19 +434,70 @@ func (e EPUBImageProcessor) loadCbz() (totalImages int, output chan task, err er
 // load a rar file that include images
 func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err error) {
 	var isSolid bool
-	files, err := rardecode.List(e.Input)
+	files, err := rardecode.List(e.Input, e.rarOptions()...)
 	if err != nil {
+		if perr := e.passwordError(err); perr != nil {
+			err = perr
+		}
 		return
 	}
 
+	// lookup images by content
+	r, err := rardecode.OpenReader(e.Input, e.rarOptions()...)
+	if err != nil {
+		return
+	}
 	names := make([]string, 0)
-	for _, f := range files {
-		if !f.IsDir && e.isSupportedImage(f.Name) {
-			if f.Solid {
-				isSolid = true
+	hasNestedArchive := false
+	for {
+		f, rerr := r.Next()
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			_ = r.Close()
+			err = rerr
+			if perr := e.passwordError(rerr); perr != nil {
+				err = perr
 			}
-			names = append(names, f.Name)
+			return
+		}
+		if f.IsDir {
+			continue
 		}
+		if e.isSupportedArchive(f.Name) {
+			hasNestedArchive = true
+			continue
+		}
+		head, rerr := e.readHead(r)
+		if rerr == nil {
+			rerr = e.sniffImageHead(head)
+		}
+		if errors.Is(rerr, errNestedArchive) {
+			hasNestedArchive = true
+			continue
+		}
+		if rerr != nil {
+			// rar 4 has no password check, a wrong password is detected with the checksum of the file
+			if f.Encrypted {
+				if _, cerr := io.Copy(io.Discard, r); cerr != nil {
+					_ = r.Close()
+					err = errInvalidPassword
+					return
+				}
+			}
+			e.skip(f.Name, rerr)
+			continue
+		}
+		if f.Solid {
+			isSolid = true
+		}
+		names = append(names, f.Name)
+	}
+	_ = r.Close()
+
+	if hasNestedArchive {
+		return e.loadNested()
 	}
 
 	totalImages = len(names)
@@ -301,6 +507,7 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 	}
 
 	sort.Sort(sortpath.By(names, e.SortPathMode))
+	e.setSources(names)
 
 	indexedNames := make(map[string]int)
 	for i, name := range names {
@@ -311,15 +518,18 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 		Id   int
 		Name string
 		Open func() (io.ReadCloser, error)
+		// the solid stream can't be read anymore
+		Err error
 	}
 
 	jobs := make(chan job)
 	go func() {
 		defer close(jobs)
-		if isSolid && !e.Dry {
-			r, rerr := rardecode.OpenReader(e.Input)
+		if isSolid && e.decode() {
+			r, rerr := rardecode.OpenReader(e.Input, e.rarOptions()...)
 			if rerr != nil {
-				utils.Fatalf("\nerror processing image %s: %s\n", e.Input, rerr)
+				jobs <- job{Name: e.Input, Err: inputError{e.Input, rerr}}
+				return
 			}
 			defer func(r *rardecode.ReadCloser) {
 				_ = r.Close()
@@ -327,18 +537,19 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 			for {
 				f, rerr := r.Next()
 				if rerr != nil {
-					if rerr == io.EOF {
-						break
+					if rerr != io.EOF {
+						jobs <- job{Name: e.Input, Err: inputError{e.Input, rerr}}
 					}
-					utils.Fatalf("\nerror processing image %s: %s\n", f.Name, rerr)
+					return
 				}
 				if i, ok := indexedNames[f.Name]; ok {
 					var b bytes.Buffer
 					_, rerr = io.Copy(&b, r)
 					if rerr != nil {
-						utils.Fatalf("\nerror processing image %s: %s\n", f.Name, rerr)
+						jobs <- job{Id: i, Name: f.Name, Err: inputError{f.Name, rerr}}
+						return
 					}
-					jobs <- job{i, f.Name, func() (io.ReadCloser, error) {
+					jobs <- job{Id: i, Name: f.Name, Open: func() (io.ReadCloser, error) {
 						return io.NopCloser(bytes.NewReader(b.Bytes())), nil
 					}}
 				}
@@ -346,7 +557,7 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 		} else {
 			for _, img := range files {
 				if i, ok := indexedNames[img.Name]; ok {
-					jobs <- job{i, img.Name, img.Open}
+					jobs <- job{Id: i, Name: img.Name, Open: img.Open}
 				}
 			}
 		}
@@ -360,16 +571,158 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
+				if job.Err != nil {
+					output <- task{Id: job.Id, Name: job.Name, Error: job.Err}
+					continue
+				}
+
 				var img image.Image
 				var err error
-				if !e.Dry {
+				if e.decode() {
 					var f io.ReadCloser
 					f, err = job.Open()
 					if err == nil {
-						img, _, err = image.Decode(f)
+						img, err = e.decodeImage(f)
+						_ = f.Close()
 					}
+				}
+
+				p, fn := filepath.Split(filepath.Clean(job.Name))
+				if err != nil {
+					img = e.corruptedImage(p, fn)
+				}
+				output <- task{
+					Id:    job.Id,
+					Image: img,
+					Path:  p,
+					Name:  fn,
+					Error: err,
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(output)
+	}()
+	return
+}
+
+// load a 7z file that include images
+func (e EPUBImageProcessor) loadCb7() (totalImages int, output chan task, err error) {
+	r, err := sevenzip.OpenReaderWithPassword(e.Input, e.Password)
+	if err != nil {
+		if perr := e.passwordError(err); perr != nil {
+			err = perr
+		}
+		return
+	}
+
+	// a solid archive store multiple files in the same stream
+	var isSolid bool
+	streams := make(map[int]bool)
+	images := make([]*sevenzip.File, 0)
+	hasNestedArchive := false
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if e.isSupportedArchive(f.Name) {
+			hasNestedArchive = true
+			continue
+		}
+		if serr := e.sniffImage(f.Open); serr != nil {
+			if errors.Is(serr, errNestedArchive) {
+				hasNestedArchive = true
+				continue
+			}
+			if perr := e.passwordError(serr); perr != nil {
+				_ = r.Close()
+				err = perr
+				return
+			}
+			e.skip(f.Name, serr)
+			continue
+		}
+		if streams[f.Stream] {
+			isSolid = true
+		}
+		streams[f.Stream] = true
+		images = append(images, f)
+	}
+
+	if hasNestedArchive {
+		_ = r.Close()
+		return e.loadNested()
+	}
+
+	totalImages = len(images)
+
+	if totalImages == 0 {
+		_ = r.Close()
+		err = errNoImagesFound
+		return
+	}
+
+	var names []string
+	for _, img := range images {
+		names = append(names, img.Name)
+	}
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	e.setSources(names)
+
+	indexedNames := make(map[string]int)
+	for i, name := range names {
+		indexedNames[name] = i
+	}
+
+	type job struct {
+		Id   int
+		Name string
+		Open func() (io.ReadCloser, error)
+	}
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for _, img := range images {
+			if isSolid && e.decode() {
+				// read the stream in order to avoid decompressing it again for each file
+				var b bytes.Buffer
+				f, rerr := img.Open()
+				if rerr == nil {
+					_, rerr = io.Copy(&b, f)
 					_ = f.Close()
 				}
+				jobs <- job{indexedNames[img.Name], img.Name, func() (io.ReadCloser, error) {
+					if rerr != nil {
+						return nil, rerr
+					}
+					return io.NopCloser(bytes.NewReader(b.Bytes())), nil
+				}}
+			} else {
+				jobs <- job{indexedNames[img.Name], img.Name, img.Open}
+			}
+		}
+	}()
+
+	output = make(chan task, e.Workers)
+	wg := &sync.WaitGroup{}
+	for range e.WorkersRatio(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var img image.Image
+				var err error
+				if e.decode() {
+					var f io.ReadCloser
+					f, err = job.Open()
+					if err == nil {
+						img, err = e.decodeImage(f)
+						_ = f.Close()
+					}
+				}
 
 				p, fn := filepath.Split(filepath.Clean(job.Name))
 				if err != nil {
@@ -385,47 +738,202 @@ func (e EPUBImageProcessor) loadCbr() (totalImages int, output chan task, err er
 			}
 		}()
 	}
+
 	go func() {
 		wg.Wait()
 		close(output)
+		_ = r.Close()
 	}()
 	return
 }
 
-// extract image from a pdf
-func (e EPUBImageProcessor) loadPdf() (totalImages int, output chan task, err error) {
-	pdf := pdfread.Load(e.Input)
-	if pdf == nil {
-		err = fmt.Errorf("can't read pdf")
+// open a tar file, decompress it if needed
+func (e EPUBImageProcessor) openTar() (*tar.Reader, func() error, error) {
+	f, err := os.Open(e.Input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, closeTar, err := e.newTarReader(f, e.archiveFormat(e.Input, nil))
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return r, func() error {
+		_ = closeTar()
+		return f.Close()
+	}, nil
+}
+
+// create a tar reader, the decompression depends on the format of the archive: tar, tar.gz or tar.zst
+func (e EPUBImageProcessor) newTarReader(r io.Reader, format string) (*tar.Reader, func() error, error) {
+	switch format {
+	case "tar.gz":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tar.NewReader(gr), gr.Close, nil
+	case "tar.zst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tar.NewReader(zr), func() error {
+			zr.Close()
+			return nil
+		}, nil
+	default:
+		return tar.NewReader(r), func() error { return nil }, nil
+	}
+}
+
+// load a tar file that include images
+//
+// tar can only be read sequentially, so we list it first, then stream it.
+func (e EPUBImageProcessor) loadCbt() (totalImages int, output chan task, err error) {
+	r, closeTar, err := e.openTar()
+	if err != nil {
 		return
 	}
 
-	totalImages = len(pdf.Pages())
-	pageFmt := "page " + utils.FormatNumberOfDigits(totalImages)
-	output = make(chan task)
+	names := make([]string, 0)
+	hasNestedArchive := false
+	for {
+		var h *tar.Header
+		h, err = r.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			_ = closeTar()
+			return
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if e.isSupportedArchive(h.Name) {
+			hasNestedArchive = true
+			continue
+		}
+		var head []byte
+		head, err = e.readHead(r)
+		if err == nil {
+			err = e.sniffImageHead(head)
+		}
+		if errors.Is(err, errNestedArchive) {
+			hasNestedArchive = true
+			err = nil
+			continue
+		}
+		if err != nil {
+			e.skip(h.Name, err)
+			err = nil
+			continue
+		}
+		names = append(names, h.Name)
+	}
+	_ = closeTar()
+
+	if hasNestedArchive {
+		return e.loadNested()
+	}
+
+	totalImages = len(names)
+	if totalImages == 0 {
+		err = errNoImagesFound
+		return
+	}
+
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	e.setSources(names)
+
+	indexedNames := make(map[string]int)
+	for i, name := range names {
+		indexedNames[name] = i
+	}
+
+	type job struct {
+		Id   int
+		Name string
+		Data []byte
+		Err  error
+	}
+
+	jobs := make(chan job)
 	go func() {
-		defer close(output)
-		defer pdf.Close()
-		for i := range totalImages {
-			var img image.Image
-			var err error
-			if !e.Dry {
-				img, err = pdfimage.Extract(pdf, i+1)
+		defer close(jobs)
+		if !e.decode() {
+			for name, i := range indexedNames {
+				jobs <- job{Id: i, Name: name}
 			}
+			return
+		}
 
-			name := fmt.Sprintf(pageFmt, i+1)
-			if err != nil {
-				img = e.corruptedImage("", name)
+		r, closeTar, rerr := e.openTar()
+		if rerr != nil {
+			jobs <- job{Name: e.Input, Err: inputError{e.Input, rerr}}
+			return
+		}
+		defer func() {
+			_ = closeTar()
+		}()
+		for {
+			h, rerr := r.Next()
+			if rerr != nil {
+				if rerr != io.EOF {
+					jobs <- job{Name: e.Input, Err: inputError{e.Input, rerr}}
+				}
+				return
 			}
-			output <- task{
-				Id:    i,
-				Image: img,
-				Path:  "",
-				Name:  name,
-				Error: err,
+			if i, ok := indexedNames[h.Name]; ok {
+				var b bytes.Buffer
+				_, rerr = io.Copy(&b, r)
+				if rerr != nil {
+					jobs <- job{Id: i, Name: h.Name, Err: inputError{h.Name, rerr}}
+					return
+				}
+				jobs <- job{Id: i, Name: h.Name, Data: b.Bytes()}
 			}
 		}
 	}()
 
+	output = make(chan task, e.Workers)
+	wg := &sync.WaitGroup{}
+	for range e.WorkersRatio(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if job.Err != nil {
+					output <- task{Id: job.Id, Name: job.Name, Error: job.Err}
+					continue
+				}
+
+				var img image.Image
+				var err error
+				if e.decode() {
+					img, err = e.decodeImage(bytes.NewReader(job.Data))
+				}
+
+				p, fn := filepath.Split(filepath.Clean(job.Name))
+				if err != nil {
+					img = e.corruptedImage(p, fn)
+				}
+				output <- task{
+					Id:    job.Id,
+					Image: img,
+					Path:  p,
+					Name:  fn,
+					Error: err,
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(output)
+	}()
 	return
 }
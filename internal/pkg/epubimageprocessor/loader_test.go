@@ -0,0 +1,175 @@
+package epubimageprocessor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+// the name of the images by id
+func loadNames(t *testing.T, e EPUBImageProcessor) map[int]string {
+	t.Helper()
+	total, output, err := e.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[int]string{}
+	for task := range output {
+		if task.Error != nil {
+			t.Errorf("%s: %v", task.Name, task.Error)
+		}
+		if task.Image == nil || task.Image.Bounds().Dx() != 8 {
+			t.Errorf("%s: image not decoded", task.Name)
+		}
+		names[task.Id] = filepath.ToSlash(filepath.Join(task.Path, task.Name))
+	}
+	if len(names) != total {
+		t.Errorf("got %d images, want %d", len(names), total)
+	}
+	return names
+}
+
+func TestLoadCb7(t *testing.T) {
+	png := encodeImage(t, "png")
+	files := []testFile{
+		{"10.png", png},
+		{"notes.txt", []byte("not an image")},
+		{"2.png", encodeImage(t, "jpeg")},
+		{"1.png", png},
+	}
+	want := map[int]string{0: "1.png", 1: "2.png", 2: "10.png"}
+	for _, tc := range []struct {
+		name     string
+		solid    bool
+		password string
+	}{
+		{"one stream by file", false, ""},
+		{"solid", true, ""},
+		{"encrypted", false, "secret"},
+		{"solid encrypted", true, "secret"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "book.cb7")
+			if err := os.WriteFile(input, sevenZipBytes(t, tc.solid, tc.password, files...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			e := New(epuboptions.EPUBOptions{Input: input, Workers: 2, SortPathMode: 2, Password: tc.password})
+			names := loadNames(t, e)
+			if len(names) != len(want) {
+				t.Errorf("got %v, want %v", names, want)
+			}
+			for id, name := range want {
+				if names[id] != name {
+					t.Errorf("id %d: got %q, want %q", id, names[id], name)
+				}
+			}
+			if skipped := e.Skipped(); len(skipped) != 1 || skipped[0].Name != "notes.txt" {
+				t.Errorf("skipped: got %+v", skipped)
+			}
+		})
+	}
+}
+
+func TestLoadCb7Password(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "book.cb7")
+	if err := os.WriteFile(input, sevenZipBytes(t, true, "secret", testFile{"01.png", encodeImage(t, "png")}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		password string
+		err      error
+	}{
+		{"", errPasswordRequired},
+		{"wrong", errInvalidPassword},
+	} {
+		e := New(epuboptions.EPUBOptions{Input: input, Workers: 1, Password: tc.password})
+		if _, _, err := e.load(); !errors.Is(err, tc.err) {
+			t.Errorf("password %q: got %v, want %v", tc.password, err, tc.err)
+		}
+	}
+}
+
+func TestLoadCbt(t *testing.T) {
+	png := encodeImage(t, "png")
+	input := filepath.Join(t.TempDir(), "book.cbt")
+	err := os.WriteFile(input, tarBytes(t,
+		&tar.Header{Name: "chapter/", Typeflag: tar.TypeDir, Mode: 0755},
+		testFile{"chapter/2.png", png},
+		&tar.Header{Name: "chapter/3.png", Typeflag: tar.TypeSymlink, Linkname: "2.png"},
+		&tar.Header{Name: "chapter/4.png", Typeflag: tar.TypeLink, Linkname: "chapter/2.png"},
+		testFile{"chapter/1.png", png},
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the regular files are loaded, the links are neither loaded nor reported
+	e := New(epuboptions.EPUBOptions{Input: input, Workers: 2})
+	names := loadNames(t, e)
+	if want := map[int]string{0: "chapter/1.png", 1: "chapter/2.png"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if skipped := e.Skipped(); len(skipped) != 0 {
+		t.Errorf("skipped: got %+v", skipped)
+	}
+}
+
+// the error is returned instead of stopping the program
+func TestLoadCbtTruncated(t *testing.T) {
+	png := encodeImage(t, "png")
+	b := tarBytes(t, testFile{"1.png", png}, testFile{"2.png", png})
+	// in the middle of the content of the second file
+	b = b[:3*512+16]
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(tarBytes(t, testFile{"1.png", png}, testFile{"2.png", png}))
+	_ = w.Close()
+
+	for name, data := range map[string][]byte{
+		"book.cbt":    b,
+		"book.tar.gz": gz.Bytes()[:gz.Len()/2],
+	} {
+		input := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(input, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		e := New(epuboptions.EPUBOptions{Input: input, Workers: 1})
+		if _, _, err := e.load(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: got %v, want %v", name, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// the tasks failing to read the input are dropped and the first error is returned
+func TestCheckInput(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{Workers: 1})
+	input := make(chan task, 4)
+	input <- task{Id: 0, Name: "1.png"}
+	input <- task{Id: 1, Name: "2.png", Error: inputError{"2.png", io.ErrUnexpectedEOF}}
+	input <- task{Id: 2, Name: "3.png", Error: errNotAnImage}
+	input <- task{Id: 3, Name: "4.png", Error: inputError{"4.png", io.ErrClosedPipe}}
+	close(input)
+
+	output, inputErr := e.checkInput(input)
+	var ids []int
+	for task := range output {
+		ids = append(ids, task.Id)
+	}
+	if want := []int{0, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+	err := inputErr()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !strings.Contains(err.Error(), "2.png") {
+		t.Errorf("got %v", err)
+	}
+}
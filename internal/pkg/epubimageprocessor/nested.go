@@ -0,0 +1,515 @@
+package epubimageprocessor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/sortpath"
+)
+
+// an image found in the input, possibly inside a nested archive.
+//
+// the name is the full path of the image, where each nested archive is a directory.
+type nestedImage struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// archive formats detected by content, the compressed tarballs are detected by their compression.
+//
+// magic use "?" as a wildcard, like the image formats.
+var archiveFormats = []struct {
+	Format string
+	Magics []string
+}{
+	{"zip", []string{"PK\x03\x04", "PK\x05\x06"}},
+	{"rar", []string{"Rar!\x1a\x07"}},
+	{"7z", []string{"7z\xbc\xaf\x27\x1c"}},
+	{"tar.gz", []string{"\x1f\x8b"}},
+	{"tar.zst", []string{"\x28\xb5\x2f\xfd"}},
+	{"tar", []string{strings.Repeat("?", 257) + "ustar"}},
+}
+
+// only accept archives that can be read from memory
+func (e EPUBImageProcessor) isSupportedArchive(path string) bool {
+	return e.archiveFormat(path, nil) != ""
+}
+
+// format of an archive, by content first, then by the extension of its name
+func (e EPUBImageProcessor) archiveFormat(name string, head []byte) string {
+	for _, f := range archiveFormats {
+		for _, magic := range f.Magics {
+			if e.matchMagic(magic, head) {
+				return f.Format
+			}
+		}
+	}
+
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.zst") || strings.HasSuffix(name, ".tzst"):
+		return "tar.zst"
+	}
+	switch filepath.Ext(name) {
+	case ".cbt", ".tar":
+		return "tar"
+	case ".cbz", ".zip":
+		return "zip"
+	case ".cbr", ".rar":
+		return "rar"
+	case ".cb7", ".7z":
+		return "7z"
+	}
+	return ""
+}
+
+// name of the directory that replace the archive
+func (e EPUBImageProcessor) archiveDirName(path string) string {
+	name := filepath.Base(path)
+	for _, ext := range []string{".tar.gz", ".tar.zst"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[0 : len(name)-len(ext)]
+		}
+	}
+	return name[0 : len(name)-len(filepath.Ext(name))]
+}
+
+// directory replacing a nested archive, named after the archive without its extension.
+//
+// the name of the archive is kept when the directory is taken by another entry or another archive,
+// the images of both can't share the same names.
+func (e EPUBImageProcessor) nestedDirName(path string, taken map[string]bool) string {
+	dir := filepath.Join(filepath.Dir(path), e.archiveDirName(path))
+	if taken[dir] {
+		dir = path
+	}
+	taken[dir] = true
+	return dir
+}
+
+// directories of the entries, the nested archives can't take their names
+func (e EPUBImageProcessor) takenDirs(names []string) map[string]bool {
+	taken := make(map[string]bool)
+	for _, name := range names {
+		for dir := filepath.Dir(name); dir != "." && dir != string(filepath.Separator) && !taken[dir]; dir = filepath.Dir(dir) {
+			taken[dir] = true
+		}
+	}
+	return taken
+}
+
+// read fully an entry
+func (e EPUBImageProcessor) readAll(open func() (io.ReadCloser, error)) ([]byte, error) {
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(f io.ReadCloser) {
+		_ = f.Close()
+	}(f)
+	return io.ReadAll(f)
+}
+
+// size above which an entry read sequentially is written to a temporary file instead of being kept in memory
+var nestedMemoryLimit int64 = 64 << 20
+
+// files opened to list the nested archives, they are closed once the images are loaded
+type nestedFiles struct {
+	files []*os.File
+	temp  []string
+}
+
+// open an archive of the input
+func (n *nestedFiles) open(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	n.files = append(n.files, f)
+	return f, nil
+}
+
+// buffer an entry for random access, in memory up to nestedMemoryLimit, in a temporary file above
+func (n *nestedFiles) buffer(open func() (io.ReadCloser, error)) (*io.SectionReader, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(r io.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	b, err := io.ReadAll(io.LimitReader(r, nestedMemoryLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) <= nestedMemoryLimit {
+		return io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), nil
+	}
+
+	f, err := os.CreateTemp("", "go-comic-converter-*")
+	if err != nil {
+		return nil, err
+	}
+	n.files = append(n.files, f)
+	n.temp = append(n.temp, f.Name())
+	size, err := io.Copy(f, io.MultiReader(bytes.NewReader(b), r))
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(f, 0, size), nil
+}
+
+// close the files and remove the temporary ones
+func (n *nestedFiles) close() {
+	for _, f := range n.files {
+		_ = f.Close()
+	}
+	for _, name := range n.temp {
+		_ = os.Remove(name)
+	}
+	n.files, n.temp = nil, nil
+}
+
+// open a buffered entry, the readers can be used concurrently
+func (e EPUBImageProcessor) openSection(r *io.SectionReader) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(r, 0, r.Size())), nil
+	}
+}
+
+// list the files of an archive under the prefix directory.
+//
+// zip and 7z are read lazily, the entries of rar and tar need to be read sequentially and are buffered.
+func (e EPUBImageProcessor) archiveEntries(files *nestedFiles, prefix, format string, r io.ReaderAt, size int64) ([]nestedImage, error) {
+	entries := make([]nestedImage, 0)
+	add := func(name string, open func() (io.ReadCloser, error)) {
+		entries = append(entries, nestedImage{filepath.Join(prefix, filepath.Clean(name)), open})
+	}
+
+	switch format {
+	case "tar", "tar.gz", "tar.zst":
+		tr, closeTar, err := e.newTarReader(io.NewSectionReader(r, 0, size), format)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = closeTar()
+		}()
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			b, err := files.buffer(func() (io.ReadCloser, error) { return io.NopCloser(tr), nil })
+			if err != nil {
+				return nil, err
+			}
+			add(h.Name, e.openSection(b))
+		}
+	case "zip":
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				add(f.Name, e.zipOpener(f))
+			}
+		}
+	case "7z":
+		zr, err := sevenzip.NewReaderWithPassword(r, size, e.Password)
+		if err != nil {
+			if perr := e.passwordError(err); perr != nil {
+				return nil, perr
+			}
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				add(f.Name, f.Open)
+			}
+		}
+	case "rar":
+		rr, err := rardecode.NewReader(io.NewSectionReader(r, 0, size), e.rarOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			h, err := rr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				if perr := e.passwordError(err); perr != nil {
+					return nil, perr
+				}
+				return nil, err
+			}
+			if h.IsDir {
+				continue
+			}
+			b, err := files.buffer(func() (io.ReadCloser, error) { return io.NopCloser(rr), nil })
+			if err != nil {
+				return nil, err
+			}
+			add(h.Name, e.openSection(b))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+	return entries, nil
+}
+
+// files of a directory, relative to it
+func (e EPUBImageProcessor) walkFiles(dir string) ([]string, error) {
+	names := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, path[len(dir)+1:])
+		}
+		return nil
+	})
+	return names, err
+}
+
+// list all images of an archive under the prefix directory, nested archives are listed recursively.
+func (e EPUBImageProcessor) listArchive(files *nestedFiles, prefix, format string, r io.ReaderAt, size int64) ([]nestedImage, error) {
+	entries, err := e.archiveEntries(files, prefix, format, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	taken := e.takenDirs(names)
+
+	images := make([]nestedImage, 0)
+	for _, entry := range entries {
+		byName := e.isSupportedArchive(entry.Name)
+		if !byName {
+			err := e.sniffImage(entry.Open)
+			if err == nil {
+				images = append(images, entry)
+				continue
+			}
+			if !errors.Is(err, errNestedArchive) {
+				if perr := e.passwordError(err); perr != nil {
+					return nil, perr
+				}
+				e.skip(entry.Name, err)
+				continue
+			}
+		}
+
+		b, err := files.buffer(entry.Open)
+		if err != nil {
+			return nil, err
+		}
+		head, err := e.readHead(io.NewSectionReader(b, 0, b.Size()))
+		if err != nil {
+			return nil, err
+		}
+		nested, err := e.listArchive(
+			files,
+			e.nestedDirName(entry.Name, taken),
+			e.archiveFormat(entry.Name, head),
+			b,
+			b.Size(),
+		)
+		if err != nil {
+			if byName {
+				return nil, err
+			}
+			// only the content looks like an archive
+			e.skip(entry.Name, err)
+			continue
+		}
+		images = append(images, nested...)
+	}
+
+	return images, nil
+}
+
+// load the input as a tree of nested images.
+//
+// a directory can contain images and archives, an archive can contain other archives.
+// each archive become a directory named after the archive without its extension,
+// or with it if the directory already exists.
+func (e EPUBImageProcessor) loadNested() (totalImages int, output chan task, err error) {
+	// the input may have been listed before finding a nested archive
+	e.resetSkipped()
+
+	images := make([]nestedImage, 0)
+	files := &nestedFiles{}
+
+	openArchive := func(prefix, path string) error {
+		f, err := files.open(path)
+		if err != nil {
+			return err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		head, err := e.readHead(f)
+		if err != nil {
+			return err
+		}
+		nested, err := e.listArchive(files, prefix, e.archiveFormat(path, head), f, fi.Size())
+		if err != nil {
+			return err
+		}
+		images = append(images, nested...)
+		return nil
+	}
+
+	input := filepath.Clean(e.Input)
+	fi, err := os.Stat(input)
+	if err != nil {
+		return
+	}
+
+	if fi.IsDir() {
+		var names []string
+		if names, err = e.walkFiles(input); err != nil {
+			return
+		}
+		taken := e.takenDirs(names)
+		for _, name := range names {
+			path := filepath.Join(input, name)
+			byName := e.isSupportedArchive(path)
+			if !byName {
+				open := func() (io.ReadCloser, error) {
+					return os.Open(path)
+				}
+				serr := e.sniffImage(open)
+				if serr == nil {
+					images = append(images, nestedImage{name, open})
+					continue
+				}
+				if !errors.Is(serr, errNestedArchive) {
+					e.skip(name, serr)
+					continue
+				}
+			}
+			if err = openArchive(e.nestedDirName(name, taken), path); err != nil {
+				if byName {
+					break
+				}
+				// only the content looks like an archive
+				e.skip(name, err)
+				err = nil
+			}
+		}
+	} else {
+		// the input archive itself is not a directory of the toc
+		err = openArchive("", input)
+	}
+
+	if err != nil {
+		files.close()
+		return
+	}
+
+	totalImages = len(images)
+	if totalImages == 0 {
+		files.close()
+		err = errNoImagesFound
+		return
+	}
+
+	names := make([]string, 0, totalImages)
+	for _, img := range images {
+		names = append(names, img.Name)
+	}
+	sort.Sort(sortpath.By(names, e.SortPathMode))
+	e.setSources(names)
+
+	// the images are numbered by their position, the same name can come from 2 sources
+	indexedNames := make(map[string][]int)
+	for i, name := range names {
+		indexedNames[name] = append(indexedNames[name], i)
+	}
+
+	type job struct {
+		Id  int
+		Img nestedImage
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for _, img := range images {
+			ids := indexedNames[img.Name]
+			indexedNames[img.Name] = ids[1:]
+			jobs <- job{ids[0], img}
+		}
+	}()
+
+	output = make(chan task, e.Workers)
+	wg := &sync.WaitGroup{}
+	for range e.WorkersRatio(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var img image.Image
+				var err error
+				if e.decode() {
+					var f io.ReadCloser
+					f, err = job.Img.Open()
+					if err == nil {
+						img, err = e.decodeImage(f)
+						_ = f.Close()
+					}
+				}
+
+				p, fn := filepath.Split(filepath.Clean(job.Img.Name))
+				if err != nil {
+					img = e.corruptedImage(p, fn)
+				}
+				output <- task{
+					Id:    job.Id,
+					Image: img,
+					Path:  p,
+					Name:  fn,
+					Error: err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		files.close()
+		close(output)
+	}()
+	return
+}
@@ -0,0 +1,197 @@
+package epubimageprocessor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{})
+	for _, tc := range []struct {
+		name string
+		head string
+		want string
+	}{
+		{"book.cbz", "", "zip"},
+		{"book.CBR", "", "rar"},
+		{"book.7z", "", "7z"},
+		{"book.tar.gz", "", "tar.gz"},
+		{"book.tgz", "", "tar.gz"},
+		{"book.tzst", "", "tar.zst"},
+		{"book.cbt", "", "tar"},
+		{"book.cbz", "Rar!\x1a\x07\x01\x00", "rar"},
+		{"book.jpg", "PK\x03\x04", "zip"},
+		{"book", "7z\xbc\xaf\x27\x1c", "7z"},
+		{"book", strings.Repeat("\x00", 257) + "ustar\x0000", "tar"},
+		{"book.jpg", "\xff\xd8\xff", ""},
+		{"book", "", ""},
+	} {
+		if got := e.archiveFormat(tc.name, []byte(tc.head)); got != tc.want {
+			t.Errorf("%s %q: got %q, want %q", tc.name, tc.head, got, tc.want)
+		}
+	}
+}
+
+func TestNestedDirName(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{})
+	taken := e.takenDirs([]string{"vol2/01.png", "extra/vol3/01.png"})
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"vol1.cbz", "vol1"},
+		{"vol1.tar.gz", "vol1.tar.gz"},
+		{"vol2.cbz", "vol2.cbz"},
+		{"extra/vol3.cbr", filepath.Join("extra", "vol3.cbr")},
+		{"extra/vol4.tar.zst", filepath.Join("extra", "vol4")},
+		{"vol5.CBZ", "vol5"},
+	} {
+		if got := e.nestedDirName(tc.path, taken); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestLoadNested(t *testing.T) {
+	png := encodeImage(t, "png")
+	for _, tc := range []struct {
+		name    string
+		files   []testFile
+		want    []string
+		skipped []string
+		err     string
+	}{
+		{
+			name: "archives become directories",
+			files: []testFile{
+				{"01.png", png},
+				{"vol1.cbz", zipBytes(t, testFile{"01.png", png}, testFile{"02.png", png})},
+				{"vol2.cbz", zipBytes(t, testFile{"01.png", png})},
+				{"vol2/01.png", png},
+				{"misnamed.jpg", zipBytes(t, testFile{"inner.cbz", zipBytes(t, testFile{"01.png", png})})},
+			},
+			want: []string{
+				"01.png",
+				"misnamed/inner/01.png",
+				"vol1/01.png", "vol1/02.png",
+				"vol2.cbz/01.png",
+				"vol2/01.png",
+			},
+		},
+		{
+			name: "content only looking like an archive",
+			files: []testFile{
+				{"01.png", png},
+				{"vol1.cbz", zipBytes(t, testFile{"01.png", png})},
+				{"data.bin", []byte("PK\x03\x04 not an archive")},
+			},
+			want:    []string{"01.png", "vol1/01.png"},
+			skipped: []string{"data.bin"},
+		},
+		{
+			name: "broken archive",
+			files: []testFile{
+				{"01.png", png},
+				{"vol1.cbz", []byte("not an archive")},
+			},
+			err: "zip",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "book.cbz")
+			if err := os.WriteFile(input, zipBytes(t, tc.files...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			e := New(epuboptions.EPUBOptions{Input: input, Workers: 2})
+			total, output, err := e.load()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			ids := map[int]bool{}
+			for task := range output {
+				if task.Error != nil {
+					t.Errorf("%s: %v", task.Name, task.Error)
+				}
+				names = append(names, filepath.ToSlash(filepath.Join(task.Path, task.Name)))
+				ids[task.Id] = true
+			}
+			sort.Strings(names)
+			if total != len(tc.want) || strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("images: got %d %v, want %v", total, names, tc.want)
+			}
+			for id := range total {
+				if !ids[id] {
+					t.Errorf("id %d missing", id)
+				}
+			}
+
+			var skipped []string
+			for _, f := range e.Skipped() {
+				skipped = append(skipped, filepath.ToSlash(filepath.Join(f.Path, f.Name)))
+			}
+			if strings.Join(skipped, ",") != strings.Join(tc.skipped, ",") {
+				t.Errorf("skipped: got %v, want %v", skipped, tc.skipped)
+			}
+		})
+	}
+}
+
+// the large entries are buffered in temporary files, removed once the images are loaded
+func TestLoadNestedTempFiles(t *testing.T) {
+	limit := nestedMemoryLimit
+	nestedMemoryLimit = 64
+	t.Cleanup(func() {
+		nestedMemoryLimit = limit
+	})
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	png := encodeImage(t, "png")
+	if len(png) <= int(nestedMemoryLimit) {
+		t.Fatalf("the image of %d bytes is kept in memory", len(png))
+	}
+	input := filepath.Join(t.TempDir(), "book.cbz")
+	err := os.WriteFile(input, zipBytes(t,
+		testFile{"vol1.cbt", tarBytes(t, testFile{"01.png", png}, testFile{"02.png", png})},
+		testFile{"vol2.cbz", zipBytes(t, testFile{"01.png", png})},
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(epuboptions.EPUBOptions{Input: input, Workers: 1})
+	total, output, err := e.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temp, _ := os.ReadDir(tmp); len(temp) == 0 {
+		t.Error("no temporary file")
+	}
+	var names []string
+	for task := range output {
+		if task.Error != nil {
+			t.Errorf("%s: %v", task.Name, task.Error)
+		}
+		names = append(names, filepath.ToSlash(filepath.Join(task.Path, task.Name)))
+	}
+	sort.Strings(names)
+	if want := []string{"vol1/01.png", "vol1/02.png", "vol2/01.png"}; total != len(want) || strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("images: got %d %v, want %v", total, names, want)
+	}
+	if temp, _ := os.ReadDir(tmp); len(temp) != 0 {
+		t.Errorf("temporary files left: %v", temp)
+	}
+}
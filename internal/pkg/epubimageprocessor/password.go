@@ -0,0 +1,56 @@
+package epubimageprocessor
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/zipcrypt"
+)
+
+var (
+	errPasswordRequired = errors.New("archive is encrypted, password required")
+	errInvalidPassword  = errors.New("invalid password")
+)
+
+// open a file of a zip, decrypted with the password if needed
+func (e EPUBImageProcessor) zipOpener(f *zip.File) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return zipcrypt.Open(f, e.Password)
+	}
+}
+
+// options of the rar reader
+func (e EPUBImageProcessor) rarOptions() []rardecode.Option {
+	if e.Password == "" {
+		return nil
+	}
+	return []rardecode.Option{rardecode.Password(e.Password)}
+}
+
+// the archive can't be read without the right password,
+// the error is returned by the loader instead of skipping the file.
+// 7z doesn't check the password, a read error of an encrypted stream is reported instead.
+func (e EPUBImageProcessor) passwordError(err error) error {
+	var sevenZipErr *sevenzip.ReadError
+	if errors.As(err, &sevenZipErr) && sevenZipErr.Encrypted {
+		if e.Password == "" {
+			return errPasswordRequired
+		}
+		return errInvalidPassword
+	}
+
+	switch {
+	case errors.Is(err, zipcrypt.ErrPasswordRequired),
+		errors.Is(err, rardecode.ErrArchiveEncrypted),
+		errors.Is(err, rardecode.ErrArchivedFileEncrypted):
+		return errPasswordRequired
+	case errors.Is(err, zipcrypt.ErrInvalidPassword),
+		errors.Is(err, rardecode.ErrBadPassword):
+		return errInvalidPassword
+	}
+	return nil
+}
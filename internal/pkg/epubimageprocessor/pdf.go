@@ -0,0 +1,173 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+
+	pdfimage "github.com/raff/pdfreader/image"
+	"github.com/raff/pdfreader/pdfread"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
+)
+
+// a page with only 1 image that cover the page can be extracted without rendering.
+//
+// any text, vector art or multiple tiles need a rendering.
+func (e EPUBImageProcessor) isPdfFullPageImage(pdf *pdfread.PdfReaderT, page int) bool {
+	pg := pdf.Pages()[page-1]
+	resources := pdf.Dic(pdf.Att("/Resources", pg))
+	if len(pdf.Dic(resources["/Font"])) > 0 {
+		return false
+	}
+
+	xo := pdf.Dic(resources["/XObject"])
+	if len(xo) != 1 {
+		return false
+	}
+
+	var imgWidth, imgHeight int
+	for _, ref := range xo {
+		dic, _ := pdf.Stream(ref)
+		if string(dic["/Subtype"]) != "/Image" {
+			return false
+		}
+		imgWidth, imgHeight = pdf.Num(dic["/Width"]), pdf.Num(dic["/Height"])
+	}
+
+	mediaBox := pdf.Arr(pdf.Att("/MediaBox", pg))
+	if len(mediaBox) != 4 || imgWidth <= 0 || imgHeight <= 0 {
+		return false
+	}
+	var box [4]float64
+	for i, v := range mediaBox {
+		f, err := strconv.ParseFloat(string(pdf.Obj(v)), 64)
+		if err != nil {
+			return false
+		}
+		box[i] = f
+	}
+	pageWidth, pageHeight := box[2]-box[0], box[3]-box[1]
+	if pageWidth <= 0 || pageHeight <= 0 {
+		return false
+	}
+
+	// the image keep the aspect ratio of the page
+	imgRatio := float64(imgHeight) / float64(imgWidth)
+	pageRatio := pageHeight / pageWidth
+	return math.Abs(imgRatio/pageRatio-1) < 0.02
+}
+
+// a page has at least 1 image to extract without rendering
+func (e EPUBImageProcessor) hasPdfImage(pdf *pdfread.PdfReaderT, page int) bool {
+	pg := pdf.Pages()[page-1]
+	resources := pdf.Dic(pdf.Att("/Resources", pg))
+	for _, ref := range pdf.Dic(resources["/XObject"]) {
+		dic, _ := pdf.Stream(ref)
+		if string(dic["/Subtype"]) == "/Image" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderer of the pages with text, vector art or multiple images.
+//
+// the renderer use MuPDF, it is only part of the build with the mupdf tag.
+type pdfRenderer interface {
+	Render(page int) (image.Image, error)
+	Close() error
+}
+
+// extract or render the page of a pdf
+func (e EPUBImageProcessor) pdfPage(pdf *pdfread.PdfReaderT, renderer pdfRenderer, page int) (image.Image, error) {
+	if e.isPdfFullPageImage(pdf, page) {
+		return pdfimage.Extract(pdf, page)
+	}
+	if renderer == nil {
+		img, err := pdfimage.Extract(pdf, page)
+		if err == nil && img == nil {
+			err = errors.New("no image in the page")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, build with the mupdf tag to render the page", err)
+		}
+		return img, nil
+	}
+	return renderer.Render(page)
+}
+
+// extract image from a pdf, render the page if it's not a single image
+func (e EPUBImageProcessor) loadPdf() (totalImages int, output chan task, err error) {
+	pdf := pdfread.Load(e.Input)
+	if pdf == nil {
+		err = fmt.Errorf("can't read pdf")
+		return
+	}
+
+	totalImages = len(pdf.Pages())
+	if !hasPdfRenderer {
+		// without image, the page would be lost
+		toRender, noImage := 0, make([]int, 0)
+		for i := range totalImages {
+			if e.isPdfFullPageImage(pdf, i+1) {
+				continue
+			}
+			toRender++
+			if !e.hasPdfImage(pdf, i+1) {
+				noImage = append(noImage, i+1)
+			}
+		}
+		if len(noImage) > 0 {
+			pdf.Close()
+			err = fmt.Errorf("%d pages have text or vector art only, starting with page %d, build with the mupdf tag to render them", len(noImage), noImage[0])
+			return
+		}
+		if toRender > 0 && e.decode() {
+			utils.Printf("Warning: %s: %d pages have text, vector art or multiple images, only their images are extracted. Build with the mupdf tag to render them.\n", e.Input, toRender)
+		}
+	}
+
+	var renderer pdfRenderer
+	if e.decode() {
+		if renderer, err = e.newPdfRenderer(); err != nil {
+			pdf.Close()
+			return
+		}
+	}
+
+	pageFmt := "page " + utils.FormatNumberOfDigits(totalImages)
+	output = make(chan task)
+	go func() {
+		defer close(output)
+		defer pdf.Close()
+		if renderer != nil {
+			defer func(renderer pdfRenderer) {
+				_ = renderer.Close()
+			}(renderer)
+		}
+		for i := range totalImages {
+			var img image.Image
+			var err error
+			if e.decode() {
+				img, err = e.pdfPage(pdf, renderer, i+1)
+			}
+
+			name := fmt.Sprintf(pageFmt, i+1)
+			if err != nil {
+				img = e.corruptedImage("", name)
+			}
+			output <- task{
+				Id:    i,
+				Image: img,
+				Path:  "",
+				Name:  name,
+				Error: err,
+			}
+		}
+	}()
+
+	return
+}
@@ -0,0 +1,54 @@
+//go:build mupdf
+
+package epubimageprocessor
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// the pages with text or vector art are rendered
+const hasPdfRenderer = true
+
+// render a page at this resolution if the view is unknown
+const defaultPdfDPI = 300
+
+// MuPDF renderer, embedded with CGO, loaded at runtime from libmupdf otherwise
+type fitzRenderer struct {
+	doc *fitz.Document
+	// size of the view
+	width, height int
+}
+
+func (e EPUBImageProcessor) newPdfRenderer() (pdfRenderer, error) {
+	doc, err := fitz.New(e.Input)
+	if err != nil {
+		return nil, fmt.Errorf("pdf renderer: %w", err)
+	}
+	return fitzRenderer{doc, e.Image.View.Width, e.Image.View.Height}, nil
+}
+
+// compute the dpi to render the page at least at the size of the view
+func (r fitzRenderer) dpi(page int) float64 {
+	bounds, err := r.doc.Bound(page)
+	if err != nil || bounds.Dx() <= 0 || bounds.Dy() <= 0 || r.width <= 0 || r.height <= 0 {
+		return defaultPdfDPI
+	}
+	// bounds are in points, 72 per inch
+	return 72 * math.Max(
+		float64(r.width)/float64(bounds.Dx()),
+		float64(r.height)/float64(bounds.Dy()),
+	)
+}
+
+// Render the page, starting at 1
+func (r fitzRenderer) Render(page int) (image.Image, error) {
+	return r.doc.ImageDPI(page-1, r.dpi(page-1))
+}
+
+func (r fitzRenderer) Close() error {
+	return r.doc.Close()
+}
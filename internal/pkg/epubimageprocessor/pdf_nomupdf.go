@@ -0,0 +1,10 @@
+//go:build !mupdf
+
+package epubimageprocessor
+
+// the tool is built without the renderer, only the images of the pages can be extracted
+const hasPdfRenderer = false
+
+func (e EPUBImageProcessor) newPdfRenderer() (pdfRenderer, error) {
+	return nil, nil
+}
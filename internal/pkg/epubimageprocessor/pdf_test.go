@@ -0,0 +1,71 @@
+package epubimageprocessor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raff/pdfreader/pdfread"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+// the first page is a jpeg covering the page, the second a black rectangle
+const vectorPdf = "vector.pdf"
+
+func TestPdfPages(t *testing.T) {
+	pdf := pdfread.Load(filepath.Join("testdata", vectorPdf))
+	if pdf == nil {
+		t.Fatal("can't read pdf")
+	}
+	defer pdf.Close()
+
+	e := New(epuboptions.EPUBOptions{})
+	for _, tc := range []struct {
+		page      int
+		fullImage bool
+		hasImage  bool
+	}{
+		{1, true, true},
+		{2, false, false},
+	} {
+		if got := e.isPdfFullPageImage(pdf, tc.page); got != tc.fullImage {
+			t.Errorf("page %d: full page image: got %v", tc.page, got)
+		}
+		if got := e.hasPdfImage(pdf, tc.page); got != tc.hasImage {
+			t.Errorf("page %d: has image: got %v", tc.page, got)
+		}
+	}
+}
+
+// the vector page is rendered with MuPDF, the conversion fails without it instead of losing the page
+func TestLoadPdfVectorPage(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{
+		Input:   filepath.Join("testdata", vectorPdf),
+		Workers: 1,
+		Image:   epuboptions.Image{View: epuboptions.View{Width: 160, Height: 240}},
+	})
+	total, output, err := e.load()
+	if !hasPdfRenderer {
+		if err == nil || !strings.Contains(err.Error(), "starting with page 2") || !strings.Contains(err.Error(), "mupdf") {
+			t.Errorf("got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Errorf("got %d pages, want 2", total)
+	}
+	for task := range output {
+		if task.Error != nil {
+			t.Errorf("%s: %v", task.Name, task.Error)
+			continue
+		}
+		// the page is extracted at the size of the image, or rendered at the size of the view
+		if want := []int{8, 160}[task.Id]; task.Image.Bounds().Dx() != want {
+			t.Errorf("%s: got a width of %d, want %d", task.Name, task.Image.Bounds().Dx(), want)
+		}
+	}
+}
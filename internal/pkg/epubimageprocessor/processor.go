@@ -2,6 +2,7 @@
 package epubimageprocessor
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
@@ -9,39 +10,121 @@ import (
 
 	"github.com/disintegration/gift"
 
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimagefilters"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubprogress"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubzip"
-	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
 )
 
 type EPUBImageProcessor struct {
 	epuboptions.EPUBOptions
+	skipped *skippedFiles
+	pages   *comicInfoPages
 }
 
 func New(o epuboptions.EPUBOptions) EPUBImageProcessor {
-	return EPUBImageProcessor{o}
+	return EPUBImageProcessor{o, &skippedFiles{}, &comicInfoPages{}}
+}
+
+// CoverId id of the image used as cover.
+//
+// the front cover of the ComicInfo.xml if any, the first image otherwise.
+func (e EPUBImageProcessor) CoverId() int {
+	// the pages are sliced from the strips in webtoon mode
+	if e.Image.Webtoon {
+		return 0
+	}
+	e.pages.mut.Lock()
+	defer e.pages.mut.Unlock()
+	if e.pages.hasCover && e.Image.HasCover {
+		return e.pages.coverId
+	}
+	return 0
+}
+
+// drop the images marked as deleted in the ComicInfo.xml, the others are renumbered to keep the ids contiguous
+func (e EPUBImageProcessor) dropDeleted(imageCount int, imageInput chan task) (int, chan task) {
+	e.indexPages(imageCount)
+	ids := e.pages.ids
+	deleted := 0
+	for _, id := range ids {
+		if id < 0 {
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return imageCount, imageInput
+	}
+
+	output := make(chan task, e.Workers)
+	go func() {
+		defer close(output)
+		for input := range imageInput {
+			if input.Id < len(ids) {
+				if ids[input.Id] < 0 {
+					continue
+				}
+				input.Id = ids[input.Id]
+			}
+			output <- input
+		}
+	}()
+	return imageCount - deleted, output
 }
 
 // Load extract and convert images
 func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	images = make([]epubimage.EPUBImage, 0)
+
+	// the margins of all the pages are looked up first
+	var uniformCrop map[int]image.Rectangle
+	if e.uniformCrop() {
+		if uniformCrop, err = e.uniformCropBoxes(); err != nil {
+			return nil, err
+		}
+		// the input is listed again
+		e.resetSkipped()
+	}
+
 	imageCount, imageInput, err := e.load()
 	if err != nil {
 		return nil, err
 	}
+	if err = e.unsupportedPages(); err != nil {
+		for range imageInput {
+		}
+		return nil, err
+	}
+	imageInput, inputErr := e.checkInput(imageInput)
+	imageCount, imageInput = e.dropDeleted(imageCount, imageInput)
+	if imageCount == 0 {
+		return nil, errNoImagesFound
+	}
 
 	// dry run, skip conversion
 	if e.Dry {
+		coverId := e.CoverId()
 		for img := range imageInput {
-			images = append(images, epubimage.EPUBImage{
-				Id:     img.Id,
-				Path:   img.Path,
-				Name:   img.Name,
-				Format: e.Image.Format,
-			})
+			i := epubimage.EPUBImage{
+				Id:       img.Id,
+				Path:     img.Path,
+				Name:     img.Name,
+				Format:   e.Image.Format,
+				Bookmark: e.bookmark(img.Id),
+			}
+			if img.Image != nil && !(e.Image.HasCover && img.Id == coverId) {
+				b := img.Image.Bounds()
+				if e.page(img.Id).DoublePage || b.Dx() > b.Dy() {
+					i.DoublePage = true
+					i.SplitPosition, i.SplitOnGutter = e.splitPosition(img.Image)
+				}
+			}
+			images = append(images, i)
+		}
+		if err = inputErr(); err != nil {
+			return nil, err
 		}
 
 		return images, nil
@@ -53,6 +136,7 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	bar := epubprogress.New(epubprogress.Options{
 		Quiet:       e.Quiet,
 		Json:        e.Json,
+		Input:       e.Input,
 		Max:         imageCount,
 		Description: "Processing",
 		CurrentJob:  1,
@@ -60,6 +144,16 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	})
 	wg := &sync.WaitGroup{}
 
+	// the progression follows the strips in webtoon mode
+	if e.Image.Webtoon {
+		if uniformCrop != nil {
+			imageInput = e.webtoonUniformCrop(imageInput, uniformCrop)
+		}
+		imageInput = e.webtoon(imageInput, func() {
+			_ = bar.Add(1)
+		})
+	}
+
 	imgStorage, err := epubzip.NewStorageImageWriter(e.ImgStorage(), e.Image.Format)
 	if err != nil {
 		_ = bar.Close()
@@ -70,23 +164,45 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	if e.Image.Format == "png" {
 		wr = 100
 	}
+	// the first image that can't be stored fails the book, the remaining images are drained
+	var storeErr error
+	storeMut := &sync.Mutex{}
+	store := func(input task, img epubimage.EPUBImage) bool {
+		err := imgStorage.Add(img.EPUBImgPath(), img.Raw, e.Image.Quality)
+		storeMut.Lock()
+		defer storeMut.Unlock()
+		if err != nil && storeErr == nil {
+			storeErr = fmt.Errorf("error with %s: %w", input.Name, err)
+		}
+		return storeErr == nil
+	}
+
+	coverId := e.CoverId()
 	for range e.WorkersRatio(wr) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
 			for input := range imageInput {
+				// before the crop, the specks don't stop the lookup of the margins
+				if e.Image.Denoise > 0 {
+					input.Image = e.denoise(input.Image)
+				}
+				// the ids of the webtoon are the slices, the pages are cropped before
+				if !e.Image.Webtoon {
+					input.UniformCrop = uniformCrop[input.Id]
+				}
+
 				img := e.transformImage(input, 0, e.Image.Manga)
 
 				// do not keep double page if requested
-				if !(img.DoublePage && input.Id > 0 &&
+				if !(img.DoublePage && input.Id != coverId &&
 					e.EPUBOptions.Image.AutoSplitDoublePage && !e.EPUBOptions.Image.KeepDoublePageIfSplit) {
-					if err = imgStorage.Add(img.EPUBImgPath(), img.Raw, e.Image.Quality); err != nil {
-						_ = bar.Close()
-						utils.Fatalf("error with %s: %s", input.Name, err)
+					if !store(input, img) {
+						continue
 					}
 					// do not keep raw image except for cover
-					if img.Id > 0 {
+					if img.Id != coverId {
 						img.Raw = nil
 					}
 					imageOutput <- img
@@ -95,15 +211,14 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 				// DOUBLE PAGE
 				if !e.Image.AutoSplitDoublePage || // No split required
 					!img.DoublePage || // Not a double page
-					(e.Image.HasCover && img.Id == 0) { // Cover
+					(e.Image.HasCover && img.Id == coverId) { // Cover
 					continue
 				}
 
 				for i, b := range []bool{e.Image.Manga, !e.Image.Manga} {
 					img = e.transformImage(input, i+1, b)
-					if err = imgStorage.Add(img.EPUBImgPath(), img.Raw, e.Image.Quality); err != nil {
-						_ = bar.Close()
-						utils.Fatalf("error with %s: %s", input.Name, err)
+					if !store(input, img) {
+						break
 					}
 					img.Raw = nil
 					imageOutput <- img
@@ -119,7 +234,7 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	}()
 
 	for img := range imageOutput {
-		if img.Part == 0 {
+		if img.Part == 0 && !e.Image.Webtoon {
 			_ = bar.Add(1)
 		}
 		if e.Image.NoBlankImage && img.IsBlank {
@@ -129,6 +244,13 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	}
 	_ = bar.Close()
 
+	if err = inputErr(); err != nil {
+		return nil, err
+	}
+	if storeErr != nil {
+		return nil, storeErr
+	}
+
 	if len(images) == 0 {
 		return nil, errNoImagesFound
 	}
@@ -136,7 +258,23 @@ func (e EPUBImageProcessor) Load() (images []epubimage.EPUBImage, err error) {
 	return images, nil
 }
 
+// dithering to the levels of gray of the device, nil if disabled
+func (e EPUBImageProcessor) ditherFilter() gift.Filter {
+	switch e.Image.Dither {
+	case 1:
+		return epubimagefilters.FloydSteinberg(e.Image.DitherLevels)
+	case 2:
+		return epubimagefilters.OrderedDither(e.Image.DitherLevels)
+	}
+	return nil
+}
+
 func (e EPUBImageProcessor) createImage(src image.Image, r image.Rectangle) draw.Image {
+	// only the levels of the dithering, the png is much smaller
+	if e.EPUBOptions.Image.Dither != 0 {
+		return image.NewPaletted(r, epubimagefilters.GrayPalette(e.EPUBOptions.Image.DitherLevels))
+	}
+
 	if e.EPUBOptions.Image.GrayScale {
 		return image.NewGray(r)
 	}
@@ -167,6 +305,19 @@ func (e EPUBImageProcessor) createImage(src image.Image, r image.Rectangle) draw
 	}
 }
 
+// remove the noise of the source, once for all the parts
+func (e EPUBImageProcessor) denoise(src image.Image) image.Image {
+	g := gift.New(epubimagefilters.Denoise(e.Image.Denoise))
+	var dst draw.Image
+	if _, ok := src.(*image.Gray); ok {
+		dst = image.NewGray(g.Bounds(src.Bounds()))
+	} else {
+		dst = image.NewNRGBA(g.Bounds(src.Bounds()))
+	}
+	g.Draw(dst, src)
+	return dst
+}
+
 // transform image into 1 or 3 images
 // only doublepage with autosplit has 3 versions
 func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epubimage.EPUBImage {
@@ -174,45 +325,68 @@ func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epu
 	src := input.Image
 	srcBounds := src.Bounds()
 
+	// area of the source kept in the page
+	cropBox := srcBounds
+
+	// split at the gutter of the area of the source
+	var splitPosition int
+	var splitOnGutter bool
+	split := func() {
+		var pos int
+		pos, splitOnGutter = epubimagefilters.SplitDoublePagePosition(src, cropBox)
+		splitPosition = cropBox.Min.X + pos
+		f := epubimagefilters.CropSplitDoublePage(right, pos)
+		g.Add(f)
+		cropBox = f.Bounds(cropBox)
+	}
+
+	// the uniform crop box is applied before the split, the halves keep the margins of the group
+	uniform := !input.UniformCrop.Empty()
+
 	// In portrait only, we don't need to keep aspect ratio between each split.
 	// We first cut, the crop.
-	if part > 0 && !e.Image.KeepSplitDoublePageAspect {
-		g.Add(epubimagefilters.CropSplitDoublePage(right))
+	if part > 0 && !e.Image.KeepSplitDoublePageAspect && !uniform {
+		split()
 	}
 
 	// Lookup for margin if crop is enable or if we want to remove blank image
 	if e.Image.Crop.Enabled || e.Image.NoBlankImage {
-		f := epubimagefilters.AutoCrop(
-			src,
-			g.Bounds(src.Bounds()),
-			e.Image.Crop.Left,
-			e.Image.Crop.Up,
-			e.Image.Crop.Right,
-			e.Image.Crop.Bottom,
-			e.Image.Crop.Limit,
-			e.Image.Crop.SkipIfLimitReached,
-		)
-
-		// detect if blank image
-		size := f.Bounds(srcBounds)
-		isBlank := size.Dx() == 0 && size.Dy() == 0
+		// the box of the uniform crop already includes the content of the page
+		r := input.UniformCrop
+		var isBlank bool
+		if !uniform {
+			r = e.cropBounds(src, g.Bounds(src.Bounds()))
+			// the slices of the webtoon keep the width of the strips cropped with the uniform box
+			if e.Image.Webtoon && e.uniformCrop() && !r.Empty() {
+				r.Min.X, r.Max.X = cropBox.Min.X, cropBox.Max.X
+			}
+			// detect if blank image
+			isBlank = r.Dx() == 0 && r.Dy() == 0
+		} else if e.Image.NoBlankImage {
+			// the box of the group has content, the page is checked alone
+			if b := e.cropBounds(src, srcBounds); b.Dx() == 0 && b.Dy() == 0 {
+				r, isBlank = b, true
+			}
+		}
 
 		// crop is enable or if blank image with noblankimage options
 		if e.Image.Crop.Enabled || (e.Image.NoBlankImage && isBlank) {
-			g.Add(f)
+			g.Add(gift.Crop(r))
+			cropBox = r
 		}
 	}
 
 	// With landscape support, we need to keep aspect ratio between each split
 	// We first crop, then cut
-	if part > 0 && e.Image.KeepSplitDoublePageAspect {
-		g.Add(epubimagefilters.CropSplitDoublePage(right))
+	if part > 0 && (e.Image.KeepSplitDoublePageAspect || uniform) {
+		split()
 	}
 
 	dstBounds := g.Bounds(src.Bounds())
-	// Original && Cropped version need to landscape oriented
+	// Original && Cropped version need to landscape oriented, unless the ComicInfo.xml flag it
 	// Only part 0 can be a double page
-	isDoublePage := part == 0 && srcBounds.Dx() > srcBounds.Dy() && dstBounds.Dx() > dstBounds.Dy()
+	page := e.page(input.Id)
+	isDoublePage := part == 0 && (page.DoublePage || srcBounds.Dx() > srcBounds.Dy() && dstBounds.Dx() > dstBounds.Dy())
 
 	if e.Image.AutoRotate && isDoublePage {
 		g.Add(gift.Rotate90())
@@ -230,10 +404,20 @@ func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epu
 		g.Add(gift.Brightness(float32(e.Image.Brightness)))
 	}
 
+	if e.Image.Gamma == -1 {
+		g.Add(epubimagefilters.AutoGamma(e.Image.BlackPoint, e.Image.WhitePoint))
+	} else if e.Image.Gamma != 1 || e.Image.BlackPoint != 0 || e.Image.WhitePoint != 255 {
+		g.Add(epubimagefilters.Levels(e.Image.BlackPoint, e.Image.WhitePoint, e.Image.Gamma))
+	}
+
 	if e.Image.Resize {
 		g.Add(gift.ResizeToFit(e.Image.View.Width, e.Image.View.Height, gift.LanczosResampling))
 	}
 
+	if e.Image.Sharpen > 0 {
+		g.Add(epubimagefilters.Sharpen(e.Image.Sharpen))
+	}
+
 	if e.Image.GrayScale {
 		var f gift.Filter
 		switch e.Image.GrayScaleMode {
@@ -253,6 +437,11 @@ func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epu
 		g.Add(f)
 	}
 
+	// after the resize, to keep the pattern of the dithering
+	if f := e.ditherFilter(); f != nil {
+		g.Add(f)
+	}
+
 	g.Add(epubimagefilters.Pixel())
 
 	dst := e.createImage(src, g.Bounds(src.Bounds()))
@@ -268,6 +457,10 @@ func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epu
 		DoublePage:          isDoublePage,
 		Path:                input.Path,
 		Name:                input.Name,
+		Bookmark:            page.Bookmark,
+		Crop:                cropBox,
+		SplitPosition:       splitPosition,
+		SplitOnGutter:       splitOnGutter,
 		Format:              e.Image.Format,
 		OriginalAspectRatio: float64(src.Bounds().Dy()) / float64(src.Bounds().Dx()),
 		Error:               input.Error,
@@ -275,6 +468,57 @@ func (e EPUBImageProcessor) transformImage(input task, part int, right bool) epu
 
 }
 
+// area of the image without the margins
+func (e EPUBImageProcessor) cropBounds(src image.Image, bounds image.Rectangle) image.Rectangle {
+	return epubimagefilters.AutoCropBounds(
+		src,
+		bounds,
+		e.Image.Crop.Left,
+		e.Image.Crop.Up,
+		e.Image.Crop.Right,
+		e.Image.Crop.Bottom,
+		e.Image.Crop.Limit,
+		e.Image.Crop.SkipIfLimitReached,
+		e.Image.Crop.Border,
+		e.Image.Crop.Threshold,
+		e.Image.Crop.IgnoreArtifacts,
+	)
+}
+
+// position of the split of a double page in the source, used to report it in the dry run.
+//
+// the area is the same as the conversion: the margins are removed first when the aspect of the parts is kept.
+func (e EPUBImageProcessor) splitPosition(src image.Image) (int, bool) {
+	area := src.Bounds()
+	if e.Image.KeepSplitDoublePageAspect && e.Image.Crop.Enabled {
+		area = e.cropBounds(src, area)
+	}
+	pos, ok := epubimagefilters.SplitDoublePagePosition(src, area)
+	return area.Min.X + pos, ok
+}
+
+// the images are decoded for the conversion, and for the dry run that reports the split of the double pages
+func (e EPUBImageProcessor) decode() bool {
+	return !e.Dry || (e.DryVerbose && e.Image.AutoSplitDoublePage)
+}
+
+// page of the image in the ComicInfo.xml.
+//
+// in webtoon mode, the images are slices of the strips and don't match the pages.
+func (e EPUBImageProcessor) page(id int) comicinfo.Page {
+	if e.Image.Webtoon {
+		return comicinfo.Page{}
+	}
+	e.pages.mut.Lock()
+	defer e.pages.mut.Unlock()
+	return e.pages.pages[id]
+}
+
+// bookmark of the image in the ComicInfo.xml
+func (e EPUBImageProcessor) bookmark(id int) string {
+	return e.page(id).Bookmark
+}
+
 type CoverTitleDataOptions struct {
 	Src         image.Image
 	Name        string
@@ -311,8 +555,13 @@ func (e EPUBImageProcessor) Cover16LevelOfGray(bounds image.Rectangle) draw.Imag
 func (e EPUBImageProcessor) CoverTitleData(o CoverTitleDataOptions) (epubzip.Image, error) {
 	// Create a blur version of the cover
 	g := gift.New(epubimagefilters.CoverTitle(o.Text, o.Align, o.PctWidth, o.PctMargin, o.MaxFontSize, o.BorderSize))
+	// the cover is dithered like the pages
+	dither := e.ditherFilter()
+	if dither != nil {
+		g.Add(dither)
+	}
 	var dst draw.Image
-	if o.Name == "cover" && e.Image.GrayScale {
+	if o.Name == "cover" && e.Image.GrayScale && dither == nil {
 		dst = e.Cover16LevelOfGray(o.Src.Bounds())
 	} else {
 		dst = e.createImage(o.Src, g.Bounds(o.Src.Bounds()))
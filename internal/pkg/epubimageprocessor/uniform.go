@@ -0,0 +1,208 @@
+package epubimageprocessor
+
+import (
+	"image"
+	"sort"
+	"sync"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubprogress"
+)
+
+const (
+	// percentage of the pages of a group allowed to have less margin than the uniform crop box
+	uniformCropPercentile = 10
+)
+
+// scope of the uniform crop box
+const (
+	UniformCropNone = iota
+	UniformCropBook
+	UniformCropChapter
+)
+
+// margins of a page, in ratio of its size
+type cropMargins struct {
+	Left, Up, Right, Bottom float64
+}
+
+// margins of a page found by the analysis
+type uniformCropPage struct {
+	Id     int
+	Path   string
+	Bounds image.Rectangle
+	// area of the content of the page
+	Crop    image.Rectangle
+	Margins cropMargins
+	// the double pages get the margins of the group on each half, they don't change them
+	DoublePage bool
+	// the cover, the blank pages and the corrupted images keep their own crop
+	Skip bool
+}
+
+// check if the pages need a first analysis of their margins
+func (e EPUBImageProcessor) uniformCrop() bool {
+	return e.Image.Crop.Enabled && e.Image.Crop.Uniform != UniformCropNone && !e.Dry
+}
+
+// uniformCropBoxes look up the margins of every page, then compute one crop box per group: the book or the chapter,
+// optionally split between the odd and the even pages.
+//
+// the box keeps the content of most pages of the group, it returns the box of each page in the coordinates of the source,
+// extended to the content of the page. The conversion use it as is, the margins are not looked up again.
+func (e EPUBImageProcessor) uniformCropBoxes() (map[int]image.Rectangle, error) {
+	imageCount, imageInput, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	imageInput, inputErr := e.checkInput(imageInput)
+	imageCount, imageInput = e.dropDeleted(imageCount, imageInput)
+
+	bar := epubprogress.New(epubprogress.Options{
+		Quiet:       e.Quiet,
+		Json:        e.Json,
+		Input:       e.Input,
+		Max:         imageCount,
+		Description: "Analyzing",
+		CurrentJob:  1,
+		TotalJob:    2,
+	})
+
+	pages := make([]uniformCropPage, 0, imageCount)
+	mut := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	coverId := e.CoverId()
+	for range e.WorkersRatio(50) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for input := range imageInput {
+				p := uniformCropPage{Id: input.Id, Path: input.Path, Skip: true}
+				if input.Image != nil {
+					src := input.Image
+					if e.Image.Denoise > 0 {
+						src = e.denoise(src)
+					}
+					b := src.Bounds()
+					r := e.cropBounds(src, b)
+					p.Bounds, p.Crop = b, r
+					p.Margins = cropMargins{
+						Left:   float64(r.Min.X-b.Min.X) / float64(b.Dx()),
+						Up:     float64(r.Min.Y-b.Min.Y) / float64(b.Dy()),
+						Right:  float64(b.Max.X-r.Max.X) / float64(b.Dx()),
+						Bottom: float64(b.Max.Y-r.Max.Y) / float64(b.Dy()),
+					}
+					p.DoublePage = !e.Image.Webtoon && (e.page(input.Id).DoublePage || b.Dx() > b.Dy())
+					p.Skip = input.Error != nil ||
+						(e.Image.HasCover && input.Id == coverId && !e.Image.Webtoon) ||
+						r.Empty()
+				}
+				mut.Lock()
+				pages = append(pages, p)
+				mut.Unlock()
+				_ = bar.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	_ = bar.Close()
+	if err = inputErr(); err != nil {
+		return nil, err
+	}
+
+	// the position of the pages in the group follows the reading order
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Id < pages[j].Id
+	})
+	groups := map[string][]uniformCropPage{}
+	var keys []string
+	position := map[string]int{}
+	for _, p := range pages {
+		key := ""
+		if e.Image.Crop.Uniform == UniformCropChapter {
+			key = p.Path
+		}
+		if e.Image.Crop.UniformOddEven {
+			parity := "odd"
+			if position[key]%2 == 1 {
+				parity = "even"
+			}
+			position[key]++
+			key += "\x00" + parity
+		}
+		if p.Skip {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	boxes := map[int]image.Rectangle{}
+	for _, key := range keys {
+		samples := make([]uniformCropPage, 0, len(groups[key]))
+		for _, p := range groups[key] {
+			if !p.DoublePage {
+				samples = append(samples, p)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		m := uniformCropMargins(samples)
+		for _, p := range groups[key] {
+			b := p.Bounds
+			// the margins of a double page are the outer margins of its halves
+			w := b.Dx()
+			if p.DoublePage {
+				w /= 2
+			}
+			boxes[p.Id] = image.Rect(
+				b.Min.X+int(m.Left*float64(w)),
+				b.Min.Y+int(m.Up*float64(b.Dy())),
+				b.Max.X-int(m.Right*float64(w)),
+				b.Max.Y-int(m.Bottom*float64(b.Dy())),
+			).Union(p.Crop)
+		}
+	}
+	return boxes, nil
+}
+
+// crop the pages horizontally with their uniform box before they are stitched into the webtoon strips, the slices
+// keep the width of the strips
+func (e EPUBImageProcessor) webtoonUniformCrop(input chan task, boxes map[int]image.Rectangle) chan task {
+	output := make(chan task, e.Workers)
+	go func() {
+		defer close(output)
+		for t := range input {
+			if box, ok := boxes[t.Id]; ok && t.Image != nil {
+				b := t.Image.Bounds()
+				if img, ok := t.Image.(interface {
+					SubImage(r image.Rectangle) image.Image
+				}); ok {
+					t.Image = img.SubImage(image.Rect(box.Min.X, b.Min.Y, box.Max.X, b.Max.Y))
+				}
+			}
+			output <- t
+		}
+	}()
+	return output
+}
+
+// margins of the group, the pages with less margin than most of the others don't reduce them
+func uniformCropMargins(pages []uniformCropPage) cropMargins {
+	side := func(margin func(m cropMargins) float64) float64 {
+		values := make([]float64, len(pages))
+		for i, p := range pages {
+			values[i] = margin(p.Margins)
+		}
+		sort.Float64s(values)
+		return values[len(values)*uniformCropPercentile/100]
+	}
+	return cropMargins{
+		Left:   side(func(m cropMargins) float64 { return m.Left }),
+		Up:     side(func(m cropMargins) float64 { return m.Up }),
+		Right:  side(func(m cropMargins) float64 { return m.Right }),
+		Bottom: side(func(m cropMargins) float64 { return m.Bottom }),
+	}
+}
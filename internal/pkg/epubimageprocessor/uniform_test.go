@@ -0,0 +1,188 @@
+package epubimageprocessor
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+func TestUniformCropMargins(t *testing.T) {
+	pages := func(lefts ...float64) []uniformCropPage {
+		var p []uniformCropPage
+		for _, l := range lefts {
+			p = append(p, uniformCropPage{Margins: cropMargins{Left: l, Up: 0.1, Right: 0.2, Bottom: 0.3}})
+		}
+		return p
+	}
+	for _, tc := range []struct {
+		name  string
+		pages []uniformCropPage
+		left  float64
+	}{
+		{"one page", pages(0.2), 0.2},
+		{"smallest margin", pages(0.3, 0.2, 0.25), 0.2},
+		{"one page over 10 with less margin", pages(0.2, 0.2, 0.2, 0.2, 0.05, 0.2, 0.2, 0.2, 0.2, 0.25), 0.2},
+		{"2 pages over 10 with less margin", pages(0.2, 0.2, 0.1, 0.2, 0.05, 0.2, 0.2, 0.2, 0.2, 0.25), 0.1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := cropMargins{tc.left, 0.1, 0.2, 0.3}
+			if got := uniformCropMargins(tc.pages); got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestUniformCropBoxes(t *testing.T) {
+	usual := image.Rect(20, 30, 80, 170)
+	for _, tc := range []struct {
+		name    string
+		uniform int
+		oddEven bool
+		want    map[int]image.Rectangle
+	}{
+		{
+			"book", UniformCropBook, false,
+			map[int]image.Rectangle{
+				0: usual, 1: usual, 2: usual, 3: usual, 4: usual,
+				// the content of the page is kept
+				5: image.Rect(10, 30, 80, 170),
+				// the small content is extended to the box
+				6: usual,
+				// the halves of the double page get the margins of the book on the outer side
+				7: image.Rect(22, 30, 198, 170),
+				8: usual, 9: usual, 10: usual, 11: usual,
+				// the blank page keeps its own crop
+			},
+		},
+		{
+			"chapter", UniformCropChapter, false,
+			map[int]image.Rectangle{
+				// less than 10 pages, the smallest margins are used
+				0: image.Rect(10, 30, 80, 170), 1: image.Rect(10, 30, 80, 170), 2: image.Rect(10, 30, 80, 170),
+				3: image.Rect(10, 30, 80, 170), 4: image.Rect(10, 30, 80, 170), 5: image.Rect(10, 30, 80, 170),
+				6: image.Rect(10, 30, 80, 170),
+				7: image.Rect(11, 30, 198, 170),
+				// the chapter 2 has its own box
+				8: image.Rect(25, 35, 75, 165), 9: image.Rect(25, 35, 75, 165),
+				10: image.Rect(25, 35, 75, 165), 11: image.Rect(25, 35, 75, 165),
+			},
+		},
+		{
+			"odd and even pages", UniformCropBook, true,
+			map[int]image.Rectangle{
+				0: usual, 2: usual, 4: usual, 6: usual, 8: usual, 10: usual,
+				// the page with less margin is one of the 5 odd single pages
+				1: image.Rect(10, 30, 80, 170), 3: image.Rect(10, 30, 80, 170), 5: image.Rect(10, 30, 80, 170),
+				7: image.Rect(11, 30, 198, 170),
+				9: image.Rect(10, 30, 80, 170), 11: image.Rect(10, 30, 80, 170),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, content := range []image.Rectangle{
+				usual, usual, usual, usual, usual,
+				image.Rect(10, 30, 80, 170),
+				image.Rect(30, 40, 70, 160),
+			} {
+				writeTestPage(t, filepath.Join(dir, "chapter1", string(rune('a'+i))+".png"), 100, 200, content)
+			}
+			writeTestPage(t, filepath.Join(dir, "chapter1", "h.png"), 220, 200, image.Rect(30, 40, 190, 160))
+			for i := range 4 {
+				writeTestPage(t, filepath.Join(dir, "chapter2", string(rune('a'+i))+".png"), 100, 200, image.Rect(25, 35, 75, 165))
+			}
+			writeTestPage(t, filepath.Join(dir, "chapter2", "e.png"), 100, 200, image.Rectangle{})
+
+			e := New(epuboptions.EPUBOptions{
+				Input:   dir,
+				Workers: 2,
+				Quiet:   true,
+				Image: epuboptions.Image{
+					Crop: epuboptions.Crop{Enabled: true, Uniform: tc.uniform, UniformOddEven: tc.oddEven},
+				},
+			})
+			boxes, err := e.uniformCropBoxes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(boxes) != len(tc.want) {
+				t.Errorf("got %d boxes, want %d", len(boxes), len(tc.want))
+			}
+			for id, want := range tc.want {
+				if got := boxes[id]; got != want {
+					t.Errorf("%d: got %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+// the strips are only cropped on the sides
+func TestWebtoonUniformCrop(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{Workers: 1})
+	input := make(chan task, 3)
+	input <- task{Id: 0, Image: image.NewGray(image.Rect(0, 0, 100, 200))}
+	input <- task{Id: 1, Image: image.NewGray(image.Rect(0, 0, 100, 200))}
+	input <- task{Id: 2}
+	close(input)
+
+	want := map[int]image.Rectangle{0: image.Rect(20, 0, 80, 200), 1: image.Rect(0, 0, 100, 200)}
+	for out := range e.webtoonUniformCrop(input, map[int]image.Rectangle{0: image.Rect(20, 30, 80, 170), 2: image.Rect(20, 30, 80, 170)}) {
+		if out.Image == nil {
+			if out.Id != 2 {
+				t.Errorf("%d: image missing", out.Id)
+			}
+			continue
+		}
+		if got := out.Image.Bounds(); got != want[out.Id] {
+			t.Errorf("%d: got %v, want %v", out.Id, got, want[out.Id])
+		}
+	}
+}
+
+// the box of the group doesn't hide the blank pages
+func TestTransformImageUniformBlank(t *testing.T) {
+	box := image.Rect(20, 30, 80, 170)
+	blank := image.NewGray(image.Rect(0, 0, 100, 200))
+	for i := range blank.Pix {
+		blank.Pix[i] = 0xff
+	}
+	page := image.NewGray(image.Rect(0, 0, 100, 200))
+	copy(page.Pix, blank.Pix)
+	page.SetGray(50, 100, color.Gray{})
+
+	for _, tc := range []struct {
+		name         string
+		src          image.Image
+		noBlankImage bool
+		blank        bool
+		size         image.Point
+	}{
+		{"blank page", blank, true, true, image.Pt(1, 1)},
+		{"blank page kept", blank, false, false, box.Size()},
+		{"page with content", page, true, false, box.Size()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New(epuboptions.EPUBOptions{
+				Workers: 1,
+				Image: epuboptions.Image{
+					Crop:         epuboptions.Crop{Enabled: true, Uniform: UniformCropBook},
+					NoBlankImage: tc.noBlankImage,
+					WhitePoint:   255,
+					Gamma:        1,
+				},
+			})
+			img := e.transformImage(task{Id: 0, Image: tc.src, UniformCrop: box}, 0, false)
+			if img.IsBlank != tc.blank {
+				t.Errorf("blank: got %v, want %v", img.IsBlank, tc.blank)
+			}
+			if got := img.Raw.Bounds().Size(); got != tc.size {
+				t.Errorf("size: got %v, want %v", got, tc.size)
+			}
+		})
+	}
+}
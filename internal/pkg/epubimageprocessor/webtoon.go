@@ -0,0 +1,212 @@
+package epubimageprocessor
+
+import (
+	"image"
+	"image/draw"
+	"sort"
+
+	"github.com/disintegration/gift"
+)
+
+const (
+	// part of the page, at its end, where a gutter is looked up
+	webtoonGutterSearch = 20
+	// maximum difference of luminance in a row of a gutter
+	webtoonGutterTolerance = 0x10
+)
+
+// source of the rows of the stitched strips
+type webtoonMarker struct {
+	Y     int
+	Name  string
+	Error error
+}
+
+// webtoon strips of a directory, stitched and not sliced yet
+type webtoonStrip struct {
+	img     *image.RGBA
+	path    string
+	markers []webtoonMarker
+}
+
+// release the tasks in the order of the ids, the strips need to be stitched in the reading order
+func (e EPUBImageProcessor) ordered(input chan task) chan task {
+	output := make(chan task, e.Workers)
+	go func() {
+		defer close(output)
+		pending := map[int]task{}
+		next := 0
+		for t := range input {
+			pending[t.Id] = t
+			for {
+				t, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				output <- t
+				next++
+			}
+		}
+
+		// ids missing from the input
+		ids := make([]int, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			output <- pending[id]
+		}
+	}()
+	return output
+}
+
+// height of a page for the width of the strips, with the aspect ratio of the view
+func (e EPUBImageProcessor) webtoonPageHeight(width int) int {
+	ratio := float64(e.Image.View.Height) / float64(e.Image.View.Width)
+	if e.Image.View.AspectRatio > 0 {
+		ratio = e.Image.View.AspectRatio
+	}
+	return max(1, int(float64(width)*ratio))
+}
+
+// check if the row is a gutter: the same color from one side to the other
+func (e EPUBImageProcessor) webtoonIsGutter(img *image.RGBA, y int) bool {
+	lo, hi := 0xff, 0
+	p := img.Pix[y*img.Stride : y*img.Stride+img.Rect.Dx()*4]
+	for i := 0; i < len(p); i += 4 {
+		l := (299*int(p[i]) + 587*int(p[i+1]) + 114*int(p[i+2])) / 1000
+		lo, hi = min(lo, l), max(hi, l)
+		if hi-lo > webtoonGutterTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// position of the cut of the next page.
+//
+// the lowest gutter at the end of the page is used, the page is cut at its height otherwise.
+func (e EPUBImageProcessor) webtoonCut(img *image.RGBA, pageHeight int) (int, bool) {
+	for y := pageHeight; y >= pageHeight-pageHeight*webtoonGutterSearch/100 && y > 0; y-- {
+		if e.webtoonIsGutter(img, y) {
+			return y, true
+		}
+	}
+	return pageHeight, false
+}
+
+// append the rows of a strip, the strip is resized to the width of the previous ones
+func (s *webtoonStrip) append(t task) {
+	src := t.Image
+	width := src.Bounds().Dx()
+	if s.img != nil {
+		width = s.img.Rect.Dx()
+	}
+	if src.Bounds().Dx() != width {
+		g := gift.New(gift.Resize(width, 0, gift.LanczosResampling))
+		dst := image.NewRGBA(g.Bounds(src.Bounds()))
+		g.Draw(dst, src)
+		src = dst
+	}
+
+	height := src.Bounds().Dy()
+	y := 0
+	if s.img != nil {
+		y = s.img.Rect.Dy()
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, y+height))
+	if s.img != nil {
+		copy(img.Pix, s.img.Pix)
+	}
+	draw.Draw(img, image.Rect(0, y, width, y+height), src, src.Bounds().Min, draw.Src)
+	s.img = img
+	s.markers = append(s.markers, webtoonMarker{y, t.Name, t.Error})
+}
+
+// cut the first rows as a page, the rows after the cut minus the overlap stay in the strip
+func (s *webtoonStrip) slice(cut, overlap int) (image.Image, string, error) {
+	width := s.img.Rect.Dx()
+	page := image.NewRGBA(image.Rect(0, 0, width, cut))
+	copy(page.Pix, s.img.Pix[:cut*s.img.Stride])
+
+	name := s.markers[0].Name
+	var err error
+	markers := make([]webtoonMarker, 0, len(s.markers))
+	next := cut - overlap
+	for i, m := range s.markers {
+		if m.Y < cut && err == nil {
+			err = m.Error
+		}
+		// the source of the first row after the cut
+		if m.Y <= next && (i+1 == len(s.markers) || s.markers[i+1].Y > next) {
+			markers = append(markers, webtoonMarker{0, m.Name, nil})
+		} else if m.Y > next {
+			markers = append(markers, webtoonMarker{m.Y - next, m.Name, m.Error})
+		}
+	}
+
+	rest := s.img.Rect.Dy() - next
+	if rest > 0 {
+		img := image.NewRGBA(image.Rect(0, 0, width, rest))
+		copy(img.Pix, s.img.Pix[next*s.img.Stride:])
+		s.img, s.markers = img, markers
+	} else {
+		s.img, s.markers = nil, nil
+	}
+	return page, name, err
+}
+
+// webtoon stitch the consecutive strips of each directory, then slice them into pages.
+//
+// the pages have the aspect ratio of the view, and the cuts land in the gutters between the panels when possible.
+// when a cut goes through a panel, the next page start with an overlap.
+func (e EPUBImageProcessor) webtoon(input chan task, strip func()) chan task {
+	output := make(chan task, e.Workers)
+	go func() {
+		defer close(output)
+
+		id := 0
+		s := &webtoonStrip{}
+		emit := func(final bool) {
+			if s.img == nil {
+				return
+			}
+			pageHeight := e.webtoonPageHeight(s.img.Rect.Dx())
+			overlap := pageHeight * e.Image.WebtoonOverlap / 100
+			for s.img != nil && (s.img.Rect.Dy() > pageHeight || final) {
+				cut, isGutter := s.img.Rect.Dy(), true
+				if cut > pageHeight {
+					cut, isGutter = e.webtoonCut(s.img, pageHeight)
+				}
+				o := 0
+				if !isGutter {
+					o = overlap
+				}
+				img, name, err := s.slice(cut, o)
+				output <- task{
+					Id:    id,
+					Image: img,
+					Path:  s.path,
+					Name:  name,
+					Error: err,
+				}
+				id++
+			}
+		}
+
+		for t := range e.ordered(input) {
+			// a new directory is a new chapter
+			if s.img != nil && t.Path != s.path {
+				emit(true)
+			}
+			s.path = t.Path
+			s.append(t)
+			emit(false)
+			strip()
+		}
+		emit(true)
+	}()
+	return output
+}
@@ -0,0 +1,86 @@
+package epubimageprocessor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
+)
+
+func TestWebtoonCut(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{})
+	for _, tc := range []struct {
+		name    string
+		gutters []int
+		cut     int
+		gutter  bool
+	}{
+		{"gutter", []int{180}, 180, true},
+		{"lowest gutter", []int{170, 190}, 190, true},
+		{"gutter at the height of the page", []int{200}, 200, true},
+		{"gutter too high", []int{100}, 200, false},
+		{"no gutter", nil, 200, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cut, gutter := e.webtoonCut(testStrip(100, 400, tc.gutters...), 200)
+			if cut != tc.cut || gutter != tc.gutter {
+				t.Errorf("got %d %v, want %d %v", cut, gutter, tc.cut, tc.gutter)
+			}
+		})
+	}
+}
+
+func TestWebtoon(t *testing.T) {
+	e := New(epuboptions.EPUBOptions{
+		Workers: 1,
+		Image: epuboptions.Image{
+			View:           epuboptions.View{Width: 100, Height: 150},
+			WebtoonOverlap: 10,
+		},
+	})
+	corrupted := errors.New("corrupted")
+	input := make(chan task, 3)
+	// the strips are stitched in the order of the ids
+	input <- task{Id: 1, Image: testStrip(100, 200), Path: "ch1", Name: "b", Error: corrupted}
+	input <- task{Id: 0, Image: testStrip(100, 200), Path: "ch1", Name: "a"}
+	input <- task{Id: 2, Image: testStrip(50, 100), Path: "ch2", Name: "c"}
+	close(input)
+
+	strips := 0
+	type page struct {
+		Path, Name string
+		Height     int
+		Err        error
+	}
+	var pages []page
+	for out := range e.webtoon(input, func() { strips++ }) {
+		if out.Id != len(pages) {
+			t.Errorf("page %d: got the id %d", len(pages), out.Id)
+		}
+		if w := out.Image.Bounds().Dx(); w != map[string]int{"ch1": 100, "ch2": 50}[out.Path] {
+			t.Errorf("page %d: got the width %d", out.Id, w)
+		}
+		pages = append(pages, page{out.Path, out.Name, out.Image.Bounds().Dy(), out.Error})
+	}
+
+	want := []page{
+		{"ch1", "a", 150, nil},
+		// the page starts with the overlap of the previous one, the corrupted strip is reported once
+		{"ch1", "a", 150, corrupted},
+		// end of the chapter
+		{"ch1", "b", 130, nil},
+		{"ch2", "c", 75, nil},
+		{"ch2", "c", 32, nil},
+	}
+	if strips != 3 {
+		t.Errorf("got %d strips, want 3", strips)
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("got %+v, want %+v", pages, want)
+	}
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Errorf("page %d: got %+v, want %+v", i, pages[i], want[i])
+		}
+	}
+}
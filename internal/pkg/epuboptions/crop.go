@@ -8,4 +8,9 @@ type Crop struct {
 	Bottom             int  `yaml:"bottom" json:"bottom"`
 	Limit              int  `yaml:"limit" json:"limit"`
 	SkipIfLimitReached bool `yaml:"skip_if_limit_reached" json:"skip_if_limit_reached"`
+	Border             int  `yaml:"border" json:"border"`
+	Threshold          int  `yaml:"threshold" json:"threshold"`
+	IgnoreArtifacts    bool `yaml:"ignore_artifacts" json:"ignore_artifacts"`
+	Uniform            int  `yaml:"uniform" json:"uniform"`
+	UniformOddEven     bool `yaml:"uniform_odd_even" json:"uniform_odd_even"`
 }
@@ -1,6 +1,10 @@
 // Package epuboptions for EPUB creation.
 package epuboptions
 
+import (
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
+)
+
 type EPUBOptions struct {
 	// Output
 	Input  string `yaml:"-" json:"input"`
@@ -8,12 +12,19 @@ type EPUBOptions struct {
 	Author string `yaml:"-" json:"author"`
 	Title  string `yaml:"-" json:"title"`
 
+	// Password of encrypted archives, never saved nor displayed
+	Password string `yaml:"-" json:"-"`
+
+	// Metadata
+	ComicInfo comicinfo.ComicInfo `yaml:"-" json:"comic_info"`
+
 	//Config
-	TitlePage                  int   `yaml:"title_page" json:"title_page"`
-	LimitMb                    int   `yaml:"limit_mb" json:"limit_mb"`
-	StripFirstDirectoryFromToc bool  `yaml:"strip_first_directory" json:"strip_first_directory"`
-	SortPathMode               int   `yaml:"sort_path_mode" json:"sort_path_mode"`
-	Image                      Image `yaml:"image" json:"image"`
+	OutputFormat               string `yaml:"output_format" json:"output_format"`
+	TitlePage                  int    `yaml:"title_page" json:"title_page"`
+	LimitMb                    int    `yaml:"limit_mb" json:"limit_mb"`
+	StripFirstDirectoryFromToc bool   `yaml:"strip_first_directory" json:"strip_first_directory"`
+	SortPathMode               int    `yaml:"sort_path_mode" json:"sort_path_mode"`
+	Image                      Image  `yaml:"image" json:"image"`
 
 	// Other
 	Dry        bool `yaml:"-" json:"dry"`
@@ -34,3 +45,16 @@ func (o EPUBOptions) WorkersRatio(pct int) (nbWorkers int) {
 func (o EPUBOptions) ImgStorage() string {
 	return o.Output + ".tmp"
 }
+
+// OutputExt extension of the output file
+func (o EPUBOptions) OutputExt() string {
+	switch o.OutputFormat {
+	case "":
+		return ".epub"
+	case "kepub":
+		// kobo devices use the native reader only with this extension
+		return ".kepub.epub"
+	default:
+		return "." + o.OutputFormat
+	}
+}
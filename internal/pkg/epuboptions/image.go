@@ -1,24 +1,33 @@
 package epuboptions
 
 type Image struct {
-	Crop                      Crop   `yaml:"crop" json:"crop"`
-	Quality                   int    `yaml:"quality" json:"quality"`
-	Brightness                int    `yaml:"brightness" json:"brightness"`
-	Contrast                  int    `yaml:"contrast" json:"contrast"`
-	AutoContrast              bool   `yaml:"auto_contrast" json:"auto_contrast"`
-	AutoRotate                bool   `yaml:"auto_rotate" json:"auto_rotate"`
-	AutoSplitDoublePage       bool   `yaml:"auto_split_double_page" json:"auto_split_double_page"`
-	KeepDoublePageIfSplit     bool   `yaml:"keep_double_page_if_split" json:"keep_double_page_if_split"`
-	KeepSplitDoublePageAspect bool   `yaml:"keep_split_double_page_aspect" json:"keep_split_double_page_aspect"`
-	NoBlankImage              bool   `yaml:"no_blank_image" json:"no_blank_image"`
-	Manga                     bool   `yaml:"manga" json:"manga"`
-	HasCover                  bool   `yaml:"has_cover" json:"has_cover"`
-	View                      View   `yaml:"view" json:"view"`
-	GrayScale                 bool   `yaml:"grayscale" json:"grayscale"`
-	GrayScaleMode             int    `yaml:"grayscale_mode" json:"gray_scale_mode"` // 0 = normal, 1 = average, 2 = luminance
-	Resize                    bool   `yaml:"resize" json:"resize"`
-	Format                    string `yaml:"format" json:"format"`
-	AppleBookCompatibility    bool   `yaml:"apple_book_compatibility" json:"apple_book_compatibility"`
+	Crop                      Crop    `yaml:"crop" json:"crop"`
+	Quality                   int     `yaml:"quality" json:"quality"`
+	Brightness                int     `yaml:"brightness" json:"brightness"`
+	Contrast                  int     `yaml:"contrast" json:"contrast"`
+	Gamma                     float64 `yaml:"gamma" json:"gamma"` // 0 = default of the profile, -1 = auto
+	BlackPoint                int     `yaml:"black_point" json:"black_point"`
+	WhitePoint                int     `yaml:"white_point" json:"white_point"`
+	Sharpen                   int     `yaml:"sharpen" json:"sharpen"`
+	Denoise                   int     `yaml:"denoise" json:"denoise"`
+	AutoContrast              bool    `yaml:"auto_contrast" json:"auto_contrast"`
+	AutoRotate                bool    `yaml:"auto_rotate" json:"auto_rotate"`
+	AutoSplitDoublePage       bool    `yaml:"auto_split_double_page" json:"auto_split_double_page"`
+	KeepDoublePageIfSplit     bool    `yaml:"keep_double_page_if_split" json:"keep_double_page_if_split"`
+	KeepSplitDoublePageAspect bool    `yaml:"keep_split_double_page_aspect" json:"keep_split_double_page_aspect"`
+	NoBlankImage              bool    `yaml:"no_blank_image" json:"no_blank_image"`
+	Manga                     bool    `yaml:"manga" json:"manga"`
+	HasCover                  bool    `yaml:"has_cover" json:"has_cover"`
+	View                      View    `yaml:"view" json:"view"`
+	GrayScale                 bool    `yaml:"grayscale" json:"grayscale"`
+	GrayScaleMode             int     `yaml:"grayscale_mode" json:"gray_scale_mode"` // 0 = normal, 1 = average, 2 = luminance
+	Dither                    int     `yaml:"dither" json:"dither"`                  // 0 = none, 1 = floyd-steinberg, 2 = ordered
+	DitherLevels              int     `yaml:"dither_levels" json:"dither_levels"`
+	Resize                    bool    `yaml:"resize" json:"resize"`
+	Format                    string  `yaml:"format" json:"format"`
+	AppleBookCompatibility    bool    `yaml:"apple_book_compatibility" json:"apple_book_compatibility"`
+	Webtoon                   bool    `yaml:"webtoon" json:"webtoon"`
+	WebtoonOverlap            int     `yaml:"webtoon_overlap" json:"webtoon_overlap"`
 }
 
 func (i Image) MediaType() string {
@@ -19,6 +19,7 @@ type Options struct {
 	Description string
 	CurrentJob  int
 	TotalJob    int
+	Input       string
 }
 
 type EPUBProgress interface {
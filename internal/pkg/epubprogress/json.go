@@ -24,6 +24,7 @@ func (p *jsonprogress) Add(num int) error {
 				"total":   p.o.TotalJob,
 			},
 			"description": p.o.Description,
+			"input":       p.o.Input,
 		},
 	})
 }
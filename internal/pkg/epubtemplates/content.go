@@ -1,8 +1,11 @@
 package epubtemplates
 
 import (
+	"strings"
+
 	"github.com/beevik/etree"
 
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/comicinfo"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epuboptions"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
@@ -15,6 +18,8 @@ type Content struct {
 	Author       string
 	Publisher    string
 	UpdatedAt    string
+	ComicInfo    comicinfo.ComicInfo
+	Kobo         bool
 	ImageOptions epuboptions.Image
 	Cover        epubimage.EPUBImage
 	Images       []epubimage.EPUBImage
@@ -100,19 +105,28 @@ func (o Content) getMeta() []tag {
 		{"opf:meta", tagAttrs{"name": "original-resolution", "content": o.ImageOptions.View.Dimension()}, ""},
 		{"dc:title", tagAttrs{}, o.Title},
 		{"dc:identifier", tagAttrs{"id": "ean"}, "urn:uuid:" + o.UID},
-		{"dc:language", tagAttrs{}, "en"},
+		{"dc:language", tagAttrs{}, o.language()},
 		{"dc:creator", tagAttrs{}, o.Author},
-		{"dc:publisher", tagAttrs{}, o.Publisher},
+		{"dc:publisher", tagAttrs{}, o.publisher()},
 		{"dc:contributor", tagAttrs{}, "Go Comic Convertor"},
-		{"dc:date", tagAttrs{}, o.UpdatedAt},
+		{"dc:date", tagAttrs{}, o.date()},
 	}
 
+	metas = append(metas, o.getComicInfoMeta()...)
+
 	if o.ImageOptions.View.PortraitOnly {
 		metas = append(metas, []tag{
 			{"meta", tagAttrs{"property": "rendition:layout"}, "pre-paginated"},
 			{"meta", tagAttrs{"property": "rendition:spread"}, "none"},
 			{"meta", tagAttrs{"property": "rendition:orientation"}, "portrait"},
 		}...)
+	} else if o.Kobo {
+		// kobo devices display the spreads only in landscape
+		metas = append(metas, []tag{
+			{"meta", tagAttrs{"property": "rendition:layout"}, "pre-paginated"},
+			{"meta", tagAttrs{"property": "rendition:spread"}, "landscape"},
+			{"meta", tagAttrs{"property": "rendition:orientation"}, "auto"},
+		}...)
 	} else {
 		metas = append(metas, []tag{
 			{"meta", tagAttrs{"property": "rendition:layout"}, "pre-paginated"},
@@ -129,7 +143,25 @@ func (o Content) getMeta() []tag {
 
 	metas = append(metas, tag{"meta", tagAttrs{"name": "cover", "content": "img_cover"}, ""})
 
-	if o.Total > 1 {
+	if o.ComicInfo.Series != "" {
+		index := o.ComicInfo.Number
+		if index == "" && o.ComicInfo.Volume > 0 {
+			index = utils.IntToString(o.ComicInfo.Volume)
+		}
+		metas = append(
+			metas,
+			tag{"meta", tagAttrs{"name": "calibre:series", "content": o.ComicInfo.Series}, ""},
+			tag{"meta", tagAttrs{"id": "series", "property": "belongs-to-collection"}, o.ComicInfo.Series},
+			tag{"meta", tagAttrs{"refines": "#series", "property": "collection-type"}, "series"},
+		)
+		if index != "" {
+			metas = append(
+				metas,
+				tag{"meta", tagAttrs{"name": "calibre:series_index", "content": index}, ""},
+				tag{"meta", tagAttrs{"refines": "#series", "property": "group-position"}, index},
+			)
+		}
+	} else if o.Total > 1 {
 		metas = append(
 			metas,
 			tag{"meta", tagAttrs{"name": "calibre:series", "content": o.Title}, ""},
@@ -140,6 +172,86 @@ func (o Content) getMeta() []tag {
 	return metas
 }
 
+// Metadata name and value of the metadata, for the formats reusing the OPF metadata
+type Metadata struct {
+	Name  string
+	Value string
+}
+
+// Metadata flatten the metadata of the content.
+//
+// the name is the tag, or the name or the property of the meta.
+func (o Content) Metadata() []Metadata {
+	var metadata []Metadata
+	for _, t := range o.getMeta() {
+		switch {
+		case t.attrs["name"] != "":
+			metadata = append(metadata, Metadata{t.attrs["name"], t.attrs["content"]})
+		case t.attrs["property"] != "":
+			metadata = append(metadata, Metadata{t.attrs["property"], t.value})
+		default:
+			metadata = append(metadata, Metadata{t.name, t.value})
+		}
+	}
+	return metadata
+}
+
+// language of the book, english by default
+func (o Content) language() string {
+	if o.ComicInfo.LanguageISO != "" {
+		return o.ComicInfo.LanguageISO
+	}
+	return "en"
+}
+
+// publisher of the comic, or the one set in the options
+func (o Content) publisher() string {
+	if o.ComicInfo.Publisher != "" {
+		return o.ComicInfo.Publisher
+	}
+	return o.Publisher
+}
+
+// publication date of the comic, or the date of the conversion
+func (o Content) date() string {
+	if d := o.ComicInfo.Date(); d != "" {
+		return d
+	}
+	return o.UpdatedAt
+}
+
+// metadata from the ComicInfo.xml
+func (o Content) getComicInfoMeta() []tag {
+	var metas []tag
+
+	// the writers are already the creator when the author is not set explicitly
+	writersAsAuthor := o.Author == strings.Join(o.ComicInfo.Writers(), ", ")
+	for i, c := range o.ComicInfo.Contributors() {
+		if c.Role == "aut" && (writersAsAuthor || c.Name == o.Author) {
+			continue
+		}
+		id := "contributor" + utils.IntToString(i)
+		name := "dc:contributor"
+		if c.Role == "aut" {
+			name = "dc:creator"
+		}
+		metas = append(metas,
+			tag{name, tagAttrs{"id": id}, c.Name},
+			tag{"meta", tagAttrs{"refines": "#" + id, "property": "role", "scheme": "marc:relators"}, c.Role},
+		)
+	}
+
+	if o.ComicInfo.Summary != "" {
+		metas = append(metas, tag{"dc:description", tagAttrs{}, o.ComicInfo.Summary})
+	}
+
+	for _, genre := range o.ComicInfo.Genres() {
+		metas = append(metas, tag{"dc:subject", tagAttrs{}, genre})
+	}
+
+	return metas
+}
+
 func (o Content) getManifest() []tag {
 	var imageTags, pageTags, spaceTags []tag
 	addTag := func(img epubimage.EPUBImage, withSpace bool) {
@@ -202,6 +314,10 @@ func (o Content) getSpineAuto() []tag {
 		if isDoublePage {
 			// Center the double page then start back to comic mode (mange/normal)
 			isOnTheRight = !o.ImageOptions.Manga
+			// kobo doesn't support the center position, the double page is displayed alone
+			if o.Kobo {
+				return "rendition:spread-none"
+			}
 			return "rendition:page-spread-center"
 		}
 		if isOnTheRight {
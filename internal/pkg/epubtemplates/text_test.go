@@ -0,0 +1,64 @@
+package epubtemplates
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// the kobo reader needs the images wrapped in spans and the pages in the book columns
+func TestTextKobo(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		template string
+		kobo     bool
+		want     []string
+		not      []string
+	}{
+		{
+			name: "kepub page", template: Text, kobo: true,
+			want: []string{
+				`class="kobostylehacks"`,
+				`<div id="book-columns"><div id="book-inner"><span class="koboSpan" id="kobo.1.1"><img src="../Images/img_1_p0.jpeg"`,
+			},
+		},
+		{
+			name: "epub page", template: Text,
+			want: []string{`<img src="../Images/img_1_p0.jpeg"`},
+			not:  []string{"kobo", "book-columns"},
+		},
+		{
+			name: "kepub blank page", template: Blank, kobo: true,
+			want: []string{`class="kobostylehacks"`, `<div id="book-columns"><div id="book-inner"></div></div>`},
+			not:  []string{"koboSpan"},
+		},
+		{
+			name: "epub blank page", template: Blank,
+			not: []string{"kobo", "book-columns"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			err := template.Must(template.New("text").Parse(tc.template)).Execute(&b, map[string]any{
+				"Title":      "Image 1 Part 0",
+				"ViewPort":   "width=80,height=120",
+				"Kobo":       tc.kobo,
+				"ImagePath":  "Images/img_1_p0.jpeg",
+				"ImageStyle": "width:80px; height:120px",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(b.String(), s) {
+					t.Errorf("missing %s in\n%s", s, b.String())
+				}
+			}
+			for _, s := range tc.not {
+				if strings.Contains(b.String(), s) {
+					t.Errorf("unexpected %s in\n%s", s, b.String())
+				}
+			}
+		})
+	}
+}
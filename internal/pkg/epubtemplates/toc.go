@@ -30,6 +30,7 @@ func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []
 
 	ol := etree.NewElement("ol")
 	paths := map[string]*etree.Element{".": ol}
+	bookmarks := map[int]*etree.Element{}
 	for _, img := range images {
 		currentPath := "."
 		for _, path := range strings.Split(img.Path, string(filepath.Separator)) {
@@ -44,10 +45,38 @@ func Toc(title string, hasTitle bool, stripFirstDirectoryFromToc bool, images []
 			link.CreateText(path)
 			paths[currentPath] = t.CreateElement("ol")
 		}
+
+		// bookmark of the ComicInfo.xml, linked to the first part of the image
+		if _, ok := bookmarks[img.Id]; img.Bookmark != "" && !ok {
+			t := paths[currentPath].CreateElement("li")
+			link := t.CreateElement("a")
+			link.CreateAttr("href", img.PagePath())
+			link.CreateText(img.Bookmark)
+			bookmarks[img.Id] = t
+		}
 	}
 
-	if len(ol.ChildElements()) == 1 && stripFirstDirectoryFromToc {
-		ol = ol.FindElement("/li/ol")
+	// only the directories decide if the first one is stripped, the bookmarks around it are kept
+	if stripFirstDirectoryFromToc {
+		var dirs []*etree.Element
+		for _, t := range ol.ChildElements() {
+			if t.SelectElement("ol") != nil {
+				dirs = append(dirs, t)
+			}
+		}
+		if len(dirs) == 1 {
+			stripped := etree.NewElement("ol")
+			for _, t := range ol.ChildElements() {
+				if t != dirs[0] {
+					stripped.AddChild(t)
+					continue
+				}
+				for _, c := range t.SelectElement("ol").ChildElements() {
+					stripped.AddChild(c)
+				}
+			}
+			ol = stripped
+		}
 	}
 
 	for _, v := range ol.FindElements("//ol") {
@@ -0,0 +1,98 @@
+package epubtemplates
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimage"
+)
+
+// titles of the toc, indented by level
+func tocTitles(t *testing.T, toc string) []string {
+	t.Helper()
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(toc); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	var walk func(ol *etree.Element, indent string)
+	walk = func(ol *etree.Element, indent string) {
+		for _, li := range ol.SelectElements("li") {
+			titles = append(titles, indent+li.SelectElement("a").Text())
+			if sub := li.SelectElement("ol"); sub != nil {
+				walk(sub, indent+"  ")
+			}
+		}
+	}
+	walk(doc.FindElement("//nav/ol"), "")
+	return titles
+}
+
+func TestToc(t *testing.T) {
+	images := func(paths ...string) []epubimage.EPUBImage {
+		var r []epubimage.EPUBImage
+		for i, p := range paths {
+			r = append(r, epubimage.EPUBImage{Id: i, Path: filepath.FromSlash(p), Format: "jpeg"})
+		}
+		return r
+	}
+	withBookmarks := func(r []epubimage.EPUBImage, bookmarks map[int]string) []epubimage.EPUBImage {
+		for id, b := range bookmarks {
+			r[id].Bookmark = b
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name   string
+		strip  bool
+		images []epubimage.EPUBImage
+		want   []string
+	}{
+		{
+			name:   "directories",
+			images: images("Book/Chapter 1", "Book/Chapter 1", "Book/Chapter 2"),
+			want:   []string{"Title", "Book", "  Chapter 1", "  Chapter 2"},
+		},
+		{
+			name:   "strip the first directory",
+			strip:  true,
+			images: images("Book/Chapter 1", "Book/Chapter 1", "Book/Chapter 2"),
+			want:   []string{"Title", "Chapter 1", "Chapter 2"},
+		},
+		{
+			name:   "bookmarks in the stripped directory",
+			strip:  true,
+			images: withBookmarks(images("Book", "Book", "Book/Chapter 1"), map[int]string{1: "Scene", 2: "Fight"}),
+			want:   []string{"Title", "Scene", "Chapter 1", "  Fight"},
+		},
+		{
+			name:   "bookmarks at the root",
+			strip:  true,
+			images: withBookmarks(images("", "", ""), map[int]string{0: "Start", 2: "End"}),
+			want:   []string{"Title", "Start", "End"},
+		},
+		{
+			name:   "bookmark at the root do not prevent the strip",
+			strip:  true,
+			images: withBookmarks(images("", "Book/Chapter 1", "Book/Chapter 2"), map[int]string{0: "Start"}),
+			want:   []string{"Title", "Start", "Chapter 1", "Chapter 2"},
+		},
+		{
+			name:   "one bookmark by image",
+			images: withBookmarks(append(images("Book"), epubimage.EPUBImage{Id: 0, Part: 1, Path: "Book", Format: "jpeg", Bookmark: "Scene"}), map[int]string{0: "Scene"}),
+			want:   []string{"Title", "Book", "  Scene"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tocTitles(t, Toc("Title", false, tc.strip, tc.images))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
@@ -58,6 +58,16 @@ func (n *EPUBTree) Add(filename string) {
 	}
 }
 
+// Value name of the directory or the file
+func (n *Node) Value() string {
+	return n.value
+}
+
+// Children sub directories and files, in the order they were added
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
 func (n *Node) ChildCount() int {
 	return len(n.children)
 }
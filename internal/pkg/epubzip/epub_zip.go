@@ -7,6 +7,7 @@ package epubzip
 
 import (
 	"archive/zip"
+	"io"
 	"os"
 	"time"
 )
@@ -66,6 +67,22 @@ func (e EPUBZip) Copy(fz *zip.File) error {
 	return e.wz.Copy(fz)
 }
 
+// CopyAs Copy the file under another name, without recompressing it.
+func (e EPUBZip) CopyAs(fz *zip.File, name string) error {
+	fh := fz.FileHeader
+	fh.Name = name
+	r, err := fz.OpenRaw()
+	if err != nil {
+		return err
+	}
+	m, err := e.wz.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(m, r)
+	return err
+}
+
 // WriteRaw Write image. They are already compressed, so we write them down directly.
 func (e EPUBZip) WriteRaw(raw Image) error {
 	m, err := e.wz.CreateRaw(raw.Header)
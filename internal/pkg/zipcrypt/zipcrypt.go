@@ -0,0 +1,258 @@
+/*
+Package zipcrypt read the encrypted files of a zip archive.
+
+The legacy ZipCrypto and the WinZip AES encryption are supported,
+only the stored and deflated files can be decompressed.
+*/
+package zipcrypt
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrPasswordRequired  = errors.New("archive is encrypted, password required")
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrUnsupportedMethod = errors.New("unsupported compression method")
+)
+
+const (
+	flagEncrypted   = 0x1
+	flagDescriptor  = 0x8
+	methodAES       = 99
+	extraAES        = 0x9901
+	aesAuthCodeSize = 10
+)
+
+// IsEncrypted the file need a password to be read
+func IsEncrypted(f *zip.File) bool {
+	return f.Flags&flagEncrypted != 0
+}
+
+// Open the file of the archive, the password is only used if the file is encrypted
+func Open(f *zip.File, password string) (io.ReadCloser, error) {
+	if !IsEncrypted(f) {
+		return f.Open()
+	}
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Method == methodAES {
+		return openAES(f, raw, password)
+	}
+	return openZipCrypto(f, raw, password)
+}
+
+// decompress the decrypted data with the method of the file
+func decompress(method uint16, r io.Reader) (io.ReadCloser, error) {
+	switch method {
+	case zip.Store:
+		return io.NopCloser(r), nil
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	default:
+		return nil, ErrUnsupportedMethod
+	}
+}
+
+// ZipCrypto keys, described in the section 6.1 of the PKWARE APPNOTE
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	k := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for _, c := range []byte(password) {
+		k.update(c)
+	}
+	return k
+}
+
+func (k *zipCryptoKeys) update(c byte) {
+	k[0] = crc32.IEEETable[byte(k[0])^c] ^ (k[0] >> 8)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32.IEEETable[byte(k[2])^byte(k[1]>>24)] ^ (k[2] >> 8)
+}
+
+func (k *zipCryptoKeys) decrypt(b []byte) {
+	for i := range b {
+		t := k[2] | 2
+		b[i] ^= byte((t * (t ^ 1)) >> 8)
+		k.update(b[i])
+	}
+}
+
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+func (z zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	z.keys.decrypt(p[:n])
+	return n, err
+}
+
+// open a file encrypted with ZipCrypto.
+//
+// the last byte of the header check the password, the crc of the content is checked at the end.
+func openZipCrypto(f *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	keys := newZipCryptoKeys(password)
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	keys.decrypt(header)
+
+	check := byte(f.CRC32 >> 24)
+	if f.Flags&flagDescriptor != 0 {
+		//goland:noinspection GoDeprecation
+		check = byte(f.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		return nil, ErrInvalidPassword
+	}
+
+	rc, err := decompress(f.Method, zipCryptoReader{raw, keys})
+	if err != nil {
+		return nil, err
+	}
+	return &checksumReader{rc: rc, hash: crc32.NewIEEE(), sum: f.CRC32}, nil
+}
+
+// verify the crc of the content once fully read
+type checksumReader struct {
+	rc   io.ReadCloser
+	hash hash.Hash32
+	sum  uint32
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.rc.Read(p)
+	c.hash.Write(p[:n])
+	if err == io.EOF && c.hash.Sum32() != c.sum {
+		err = zip.ErrChecksum
+	}
+	return n, err
+}
+
+func (c *checksumReader) Close() error {
+	return c.rc.Close()
+}
+
+// open a file encrypted with WinZip AES.
+//
+// the password verification value is checked first, the authentication code is checked at the end.
+func openAES(f *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	strength, method, ok := aesExtra(f.Extra)
+	if !ok || strength < 1 || strength > 3 {
+		return nil, zip.ErrFormat
+	}
+	keySize := 8 + 8*int(strength)
+	saltSize := keySize / 2
+
+	salt := make([]byte, saltSize+2)
+	if _, err := io.ReadFull(raw, salt); err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key([]byte(password), salt[:saltSize], 1000, 2*keySize+2, sha1.New)
+	if !bytes.Equal(key[2*keySize:], salt[saltSize:]) {
+		return nil, ErrInvalidPassword
+	}
+
+	dataSize := int64(f.CompressedSize64) - int64(saltSize) - 2 - aesAuthCodeSize
+	if dataSize < 0 {
+		return nil, zip.ErrFormat
+	}
+
+	block, err := aes.NewCipher(key[:keySize])
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, key[keySize:2*keySize])
+	ar := &aesReader{
+		data:    io.TeeReader(io.LimitReader(raw, dataSize), mac),
+		raw:     raw,
+		mac:     mac,
+		block:   block,
+		counter: make([]byte, aes.BlockSize),
+	}
+
+	return decompress(method, ar)
+}
+
+// strength and compression method of the AES extra field
+func aesExtra(extra []byte) (strength byte, method uint16, ok bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			return
+		}
+		if id == extraAES && size >= 7 {
+			return extra[4], binary.LittleEndian.Uint16(extra[5:]), true
+		}
+		extra = extra[size:]
+	}
+	return
+}
+
+// decrypt the AES content, WinZip use a little endian counter starting at 1
+type aesReader struct {
+	data    io.Reader
+	raw     io.Reader
+	mac     hash.Hash
+	block   cipher.Block
+	counter []byte
+	stream  []byte
+}
+
+func (a *aesReader) Read(p []byte) (int, error) {
+	n, err := a.data.Read(p)
+	for i := range p[:n] {
+		if len(a.stream) == 0 {
+			a.increment()
+			a.stream = make([]byte, aes.BlockSize)
+			a.block.Encrypt(a.stream, a.counter)
+		}
+		p[i] ^= a.stream[0]
+		a.stream = a.stream[1:]
+	}
+	if err == io.EOF {
+		authCode := make([]byte, aesAuthCodeSize)
+		if _, rerr := io.ReadFull(a.raw, authCode); rerr != nil {
+			return n, rerr
+		}
+		if !hmac.Equal(a.mac.Sum(nil)[:aesAuthCodeSize], authCode) {
+			return n, zip.ErrChecksum
+		}
+	}
+	return n, err
+}
+
+func (a *aesReader) increment() {
+	for i := range a.counter {
+		a.counter[i]++
+		if a.counter[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package zipcrypt
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const content = "the content of the page, repeated to be compressed. the content of the page, repeated to be compressed."
+
+type entry struct {
+	name     string
+	password string
+	method   uint16
+	aes      bool
+	// data descriptor, the password check use the modification time
+	descriptor bool
+	// alter the encrypted data
+	corrupt bool
+}
+
+func compress(t *testing.T, method uint16, data []byte) []byte {
+	t.Helper()
+	if method != zip.Deflate {
+		return data
+	}
+	var b bytes.Buffer
+	w, _ := flate.NewWriter(&b, flate.BestCompression)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return b.Bytes()
+}
+
+func (k *zipCryptoKeys) encrypt(b []byte) {
+	for i, c := range b {
+		t := k[2] | 2
+		b[i] ^= byte((t * (t ^ 1)) >> 8)
+		k.update(c)
+	}
+}
+
+// archive with one file per entry, encrypted like the archivers do
+func archive(t *testing.T, entries ...entry) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		h := &zip.FileHeader{
+			Name:               e.name,
+			Method:             e.method,
+			CRC32:              crc32.ChecksumIEEE([]byte(content)),
+			UncompressedSize64: uint64(len(content)),
+		}
+		h.SetModTime(h.Modified)
+		data := compress(t, e.method, []byte(content))
+
+		switch {
+		case e.password == "":
+		case e.aes:
+			h.Flags |= flagEncrypted
+			extra := binary.LittleEndian.AppendUint16(nil, extraAES)
+			extra = binary.LittleEndian.AppendUint16(extra, 7)
+			extra = append(extra, 2, 0, 'A', 'E', 3)
+			extra = binary.LittleEndian.AppendUint16(extra, e.method)
+			h.Extra, h.Method = extra, methodAES
+
+			salt := bytes.Repeat([]byte{7}, 16)
+			key := pbkdf2.Key([]byte(e.password), salt, 1000, 66, sha1.New)
+			block, _ := aes.NewCipher(key[:32])
+			ar := &aesReader{block: block, counter: make([]byte, aes.BlockSize)}
+			encrypted := append([]byte{}, data...)
+			for i := range encrypted {
+				if len(ar.stream) == 0 {
+					ar.increment()
+					ar.stream = make([]byte, aes.BlockSize)
+					block.Encrypt(ar.stream, ar.counter)
+				}
+				encrypted[i] ^= ar.stream[0]
+				ar.stream = ar.stream[1:]
+			}
+			mac := hmac.New(sha1.New, key[32:64])
+			mac.Write(encrypted)
+			data = append(append(append(salt, key[64:]...), encrypted...), mac.Sum(nil)[:aesAuthCodeSize]...)
+		default:
+			h.Flags |= flagEncrypted
+			header := bytes.Repeat([]byte{0x5a}, 12)
+			header[11] = byte(h.CRC32 >> 24)
+			if e.descriptor {
+				h.Flags |= flagDescriptor
+				//goland:noinspection GoDeprecation
+				header[11] = byte(h.ModifiedTime >> 8)
+			}
+			keys := newZipCryptoKeys(e.password)
+			keys.encrypt(header)
+			encrypted := append([]byte{}, data...)
+			keys.encrypt(encrypted)
+			data = append(header, encrypted...)
+		}
+		if e.corrupt {
+			data[len(data)/2] ^= 0xff
+		}
+		h.CompressedSize64 = uint64(len(data))
+
+		fw, err := w.CreateRaw(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(data)
+	}
+	_ = w.Close()
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestOpen(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		entry    entry
+		password string
+		// error of the open, then of the read
+		openErr, readErr error
+	}{
+		{"plain", entry{method: zip.Deflate}, "", nil, nil},
+		{"plain with a password", entry{method: zip.Store}, "secret", nil, nil},
+		{"password required", entry{password: "secret", method: zip.Deflate}, "", ErrPasswordRequired, nil},
+		{"zipcrypto stored", entry{password: "secret", method: zip.Store}, "secret", nil, nil},
+		{"zipcrypto deflated", entry{password: "secret", method: zip.Deflate}, "secret", nil, nil},
+		{"zipcrypto descriptor", entry{password: "secret", method: zip.Deflate, descriptor: true}, "secret", nil, nil},
+		{"zipcrypto wrong password", entry{password: "secret", method: zip.Deflate}, "wrong", ErrInvalidPassword, nil},
+		{"zipcrypto bad crc", entry{password: "secret", method: zip.Store, corrupt: true}, "secret", nil, zip.ErrChecksum},
+		{"zipcrypto unsupported method", entry{password: "secret", method: 12}, "secret", ErrUnsupportedMethod, nil},
+		{"aes stored", entry{password: "secret", method: zip.Store, aes: true}, "secret", nil, nil},
+		{"aes deflated", entry{password: "secret", method: zip.Deflate, aes: true}, "secret", nil, nil},
+		{"aes wrong password", entry{password: "secret", method: zip.Deflate, aes: true}, "wrong", ErrInvalidPassword, nil},
+		{"aes bad authentication code", entry{password: "secret", method: zip.Store, aes: true, corrupt: true}, "secret", nil, zip.ErrChecksum},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.entry.name = "page.txt"
+			f := archive(t, tc.entry).File[0]
+			if got := IsEncrypted(f); got != (tc.entry.password != "") {
+				t.Errorf("encrypted: got %v", got)
+			}
+
+			rc, err := Open(f, tc.password)
+			if !errors.Is(err, tc.openErr) {
+				t.Fatalf("open: got %v, want %v", err, tc.openErr)
+			}
+			if err != nil {
+				return
+			}
+			defer func() {
+				_ = rc.Close()
+			}()
+			b, err := io.ReadAll(rc)
+			if !errors.Is(err, tc.readErr) {
+				t.Fatalf("read: got %v, want %v", err, tc.readErr)
+			}
+			if err == nil && string(b) != content {
+				t.Errorf("content: got %q", b)
+			}
+		})
+	}
+}
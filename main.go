@@ -9,13 +9,20 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/tcnksm/go-latest"
 
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/converter"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epub"
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/epubimageprocessor"
 	"github.com/celogeek/go-comic-converter/v2/internal/pkg/utils"
 )
 
@@ -108,27 +115,180 @@ func reset(cmd *converter.Converter) {
 }
 
 func generate(cmd *converter.Converter) {
+	if cmd.Options.Watch {
+		watch(cmd)
+		return
+	}
+
+	inputs, err := cmd.Inputs()
+	if err != nil {
+		cmd.Fatal(err)
+	}
+
+	if len(inputs) > 1 || cmd.Options.Batch {
+		batch(cmd, inputs)
+		return
+	}
+
+	cmd.Options.Input = inputs[0]
 	if err := cmd.Validate(); err != nil {
 		cmd.Fatal(err)
 	}
+	if err := convert(cmd); err != nil {
+		utils.Fatalf("Error: %v\n", err)
+	}
+	if !cmd.Options.Dry {
+		cmd.Stats()
+	}
+}
 
+// convert one book, the options have been validated
+func convert(cmd *converter.Converter) error {
 	if profile := cmd.Options.GetProfile(); profile != nil {
 		cmd.Options.Image.View.Width = profile.Width
 		cmd.Options.Image.View.Height = profile.Height
 	}
 
+	info, err := epubimageprocessor.New(cmd.Options.EPUBOptions).ReadComicInfo()
+	if err != nil {
+		utils.Printf("Warning: can't read ComicInfo.xml: %v\n", err)
+	} else if !info.IsEmpty() {
+		cmd.ApplyComicInfo(info)
+	}
+
 	if cmd.Options.Json {
 		_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
 			"type": "options", "data": cmd.Options,
 		})
-	} else {
+	} else if !cmd.Options.Batch {
 		utils.Println(cmd.Options)
 	}
 
-	if err := epub.New(cmd.Options.EPUBOptions).Write(); err != nil {
-		utils.Fatalf("Error: %v\n", err)
+	return epub.New(cmd.Options.EPUBOptions).Write()
+}
+
+// convert multiple books, a failure doesn't stop the others.
+//
+// the workers are shared between the books converted in parallel.
+func batch(cmd *converter.Converter, inputs []string) {
+	if strings.HasSuffix(strings.ToLower(cmd.Options.Output), cmd.Options.OutputExt()) {
+		cmd.Fatal(errors.New("output must be a directory with multiple sources"))
 	}
-	if !cmd.Options.Dry {
-		cmd.Stats()
+
+	cmd.Options.Batch = true
+	parallel := cmd.BatchParallel(len(inputs))
+	workers := max(1, cmd.Options.Workers/parallel)
+	if parallel > 1 && !cmd.Options.Json {
+		// progress bars of books converted in parallel can't be displayed together
+		cmd.Options.Quiet = true
+	}
+
+	type result struct {
+		Input   string
+		Output  string
+		Err     error
+		Elapsed time.Duration
+	}
+
+	// validate all books first, two books can't share the same output
+	books := make([]*converter.Converter, 0, len(inputs))
+	results := make([]result, 0, len(inputs))
+	outputs := map[string]string{}
+	for _, input := range inputs {
+		book := cmd.ForInput(input, workers)
+		err := book.Validate()
+		if other, ok := outputs[book.Options.Output]; ok && err == nil {
+			err = fmt.Errorf("output %s already used by %s", book.Options.Output, other)
+		}
+		if err != nil {
+			results = append(results, result{Input: input, Err: err})
+			continue
+		}
+		outputs[book.Options.Output] = input
+		books = append(books, book)
+	}
+
+	report := func(r result) {
+		status := "success"
+		errMsg := ""
+		if r.Err != nil {
+			status, errMsg = "failed", r.Err.Error()
+		}
+		if cmd.Options.Json {
+			_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
+				"type": "result",
+				"data": map[string]any{
+					"input":     r.Input,
+					"output":    r.Output,
+					"status":    status,
+					"error":     errMsg,
+					"elapse_ms": r.Elapsed.Milliseconds(),
+				},
+			})
+		} else if r.Err != nil {
+			utils.Printf("[failed] %s: %v\n", r.Input, r.Err)
+		} else {
+			utils.Printf("[success] %s -> %s (%s)\n", r.Input, r.Output, r.Elapsed)
+		}
+	}
+
+	for _, r := range results {
+		report(r)
+	}
+
+	mut := &sync.Mutex{}
+	jobs := make(chan *converter.Converter)
+	go func() {
+		defer close(jobs)
+		for _, book := range books {
+			jobs <- book
+		}
+	}()
+
+	wg := &sync.WaitGroup{}
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for book := range jobs {
+				start := time.Now()
+				err := convert(book)
+				r := result{book.Options.Input, book.Options.Output, err, time.Since(start).Round(time.Millisecond)}
+				mut.Lock()
+				results = append(results, r)
+				report(r)
+				mut.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := make([]string, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Input)
+		}
+	}
+	sort.Strings(failed)
+
+	if cmd.Options.Json {
+		_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
+			"type": "summary",
+			"data": map[string]any{
+				"total":   len(results),
+				"success": len(results) - len(failed),
+				"failed":  failed,
+			},
+		})
+	} else {
+		utils.Printf("\nConverted %d/%d books\n", len(results)-len(failed), len(results))
+		for _, input := range failed {
+			utils.Printf("  - failed: %s\n", input)
+		}
+	}
+
+	cmd.Stats()
+	if len(failed) > 0 {
+		os.Exit(1)
 	}
 }
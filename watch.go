@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/converter"
+)
+
+const (
+	watchStateFile = ".go-comic-converter-watch.json"
+	watchDoneDir   = ".done"
+)
+
+// size and last modification of a source, a source is stable when it doesn't change between 2 scans
+type watchSnapshot struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (s watchSnapshot) same(o watchSnapshot) bool {
+	return s.Size == o.Size && s.ModTime.Equal(o.ModTime)
+}
+
+// source already processed, it is converted again only if it changes
+type watchEntry struct {
+	watchSnapshot
+	Status      string    `json:"status"`
+	Output      string    `json:"output,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+type watchState struct {
+	path  string
+	Files map[string]watchEntry `json:"files"`
+}
+
+// load the state of the previous runs
+func loadWatchState(path string) (*watchState, error) {
+	s := &watchState{path: path, Files: map[string]watchEntry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if s.Files == nil {
+		s.Files = map[string]watchEntry{}
+	}
+	return s, nil
+}
+
+// save the state, the file is replaced at once to survive a crash
+func (s *watchState) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(s.path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+// output of a source, a number is added to the name if the output belongs to another source or already exists.
+//
+// 2 sources with the same name but a different extension don't overwrite each other, nor the files already in the
+// output directory. A source converted again keeps its output.
+func (s *watchState) output(name, output, ext string) string {
+	used := func(path string) bool {
+		for other, entry := range s.Files {
+			if entry.Output == path {
+				return other != name
+			}
+		}
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	base := strings.TrimSuffix(output, ext)
+	for i := 2; used(output); i++ {
+		output = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	return output
+}
+
+// snapshots of the sources not yet stable, by name
+type watchPending map[string]watchSnapshot
+
+// stable check if the source didn't change since the previous scan.
+//
+// only the scans are compared, the time of the last modification can be kept by the copy (cp -p, rsync -t).
+func (p watchPending) stable(name string, snap watchSnapshot) bool {
+	if prev, ok := p[name]; !ok || !prev.same(snap) {
+		p[name] = snap
+		return false
+	}
+	delete(p, name)
+	return true
+}
+
+// forget the sources removed before being stable
+func (p watchPending) forget(seen map[string]bool) {
+	for name := range p {
+		if !seen[name] {
+			delete(p, name)
+		}
+	}
+}
+
+// process convert a stable source and move it into the done directory, the result is saved into the state.
+//
+// a source converted but not moved is recorded as such, it is not converted again until it changes.
+func (s *watchState) process(cmd *converter.Converter, inbox, outbox, name string, snap watchSnapshot, convert func(*converter.Converter) error) {
+	path := filepath.Join(inbox, name)
+	watchEvent("converting", map[string]any{"input": path})
+	start := time.Now()
+	book := cmd.ForInput(path, cmd.Options.Workers)
+	book.Options.Output = outbox
+	err := book.Validate()
+	if err == nil {
+		book.Options.Output = s.output(name, book.Options.Output, book.Options.OutputExt())
+		err = convert(book)
+	}
+
+	entry := watchEntry{watchSnapshot: snap, Status: "success", Output: book.Options.Output, ProcessedAt: time.Now()}
+	if err != nil {
+		entry.Status, entry.Error = "failed", err.Error()
+		watchEvent("failed", map[string]any{"input": path, "error": err.Error()})
+	} else {
+		watchEvent("converted", map[string]any{
+			"input":     path,
+			"output":    book.Options.Output,
+			"elapse_ms": time.Since(start).Milliseconds(),
+		})
+		if err = moveToDone(inbox, name); err != nil {
+			entry.Status, entry.Error = "not moved", err.Error()
+			watchEvent("failed", map[string]any{"input": path, "error": err.Error()})
+		}
+	}
+
+	s.Files[name] = entry
+	if err = s.save(); err != nil {
+		watchEvent("failed", map[string]any{"input": s.path, "error": err.Error()})
+	}
+}
+
+// snapshot of a file or a directory of images
+func watchSnapshotOf(path string) (watchSnapshot, error) {
+	var snap watchSnapshot
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			snap.Size += fi.Size()
+		}
+		if fi.ModTime().After(snap.ModTime) {
+			snap.ModTime = fi.ModTime()
+		}
+		return nil
+	})
+	return snap, err
+}
+
+// emit a watch event as json
+func watchEvent(event string, data map[string]any) {
+	data["event"] = event
+	data["at"] = time.Now().Format(time.RFC3339)
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
+		"type": "watch",
+		"data": data,
+	})
+}
+
+// watch the input directory and convert the new comics into the output directory.
+//
+// the sources are converted once they stop changing, then moved into the .done directory of the inbox.
+// the state file keeps track of the processed sources, including the failures.
+func watch(cmd *converter.Converter) {
+	if cmd.Options.Input == "" || cmd.Options.Output == "" {
+		cmd.Fatal(errors.New("watch mode require an input and an output directory"))
+	}
+	inbox, outbox := filepath.Clean(cmd.Options.Input), filepath.Clean(cmd.Options.Output)
+	for _, dir := range []struct {
+		Name string
+		Path string
+	}{{"input", inbox}, {"output", outbox}} {
+		fi, err := os.Stat(dir.Path)
+		if err != nil {
+			cmd.Fatal(err)
+		}
+		if !fi.IsDir() {
+			cmd.Fatal(fmt.Errorf("watch mode require the %s to be a directory", dir.Name))
+		}
+	}
+	// the converted comics would be picked up as new sources
+	if rel, err := filepath.Rel(inbox, outbox); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		cmd.Fatal(errors.New("watch mode require the output to be outside of the input directory"))
+	}
+	if cmd.Options.WatchInterval < 1 {
+		cmd.Fatal(errors.New("watch interval should be >= 1"))
+	}
+
+	state, err := loadWatchState(filepath.Join(inbox, watchStateFile))
+	if err != nil {
+		cmd.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd.Options.Batch = true
+	cmd.Options.Quiet = true
+	cmd.Options.Json = false
+
+	interval := time.Duration(cmd.Options.WatchInterval) * time.Second
+	watchEvent("started", map[string]any{"input": inbox, "output": outbox, "interval_s": cmd.Options.WatchInterval})
+
+	pending := watchPending{}
+	for {
+		entries, err := os.ReadDir(inbox)
+		if err != nil {
+			watchEvent("failed", map[string]any{"input": inbox, "error": err.Error()})
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") || (!e.IsDir() && !cmd.IsComic(name)) {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		seen := map[string]bool{}
+		for _, name := range names {
+			if ctx.Err() != nil {
+				break
+			}
+			seen[name] = true
+			path := filepath.Join(inbox, name)
+			snap, err := watchSnapshotOf(path)
+			if err != nil {
+				// the source may have been removed while scanning
+				continue
+			}
+
+			if entry, ok := state.Files[name]; ok && entry.same(snap) {
+				continue
+			}
+
+			if !pending.stable(name, snap) {
+				continue
+			}
+			state.process(cmd, inbox, outbox, name, snap, convert)
+		}
+
+		pending.forget(seen)
+
+		select {
+		case <-ctx.Done():
+			watchEvent("stopped", map[string]any{"input": inbox})
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// move a processed source into the done directory of the inbox, without overwriting a previous one
+func moveToDone(inbox, name string) error {
+	done := filepath.Join(inbox, watchDoneDir)
+	if err := os.MkdirAll(done, 0755); err != nil {
+		return err
+	}
+	target := filepath.Join(done, name)
+	if _, err := os.Stat(target); err == nil {
+		target = filepath.Join(done, time.Now().Format("20060102-150405")+" "+name)
+	}
+	return os.Rename(filepath.Join(inbox, name), target)
+}
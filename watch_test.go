@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/celogeek/go-comic-converter/v2/internal/pkg/converter"
+)
+
+func TestWatchStateOutput(t *testing.T) {
+	out := t.TempDir()
+	p := func(name string) string {
+		return filepath.Join(out, name)
+	}
+	if err := os.WriteFile(p("existing.epub"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &watchState{Files: map[string]watchEntry{
+		"a.cbz":      {Output: p("a.epub")},
+		"b.cbz":      {Output: p("b.epub")},
+		"b.cbr":      {Output: p("b (2).epub")},
+		"failed.cbz": {Status: "failed"},
+	}}
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"new.cbz", p("new.epub"), p("new.epub")},
+		{"a.cbz", p("a.epub"), p("a.epub")},
+		{"a.cbr", p("a.epub"), p("a (2).epub")},
+		{"b.cb7", p("b.epub"), p("b (3).epub")},
+		{"b.cbr", p("b.epub"), p("b (2).epub")},
+		{"existing.cbz", p("existing.epub"), p("existing (2).epub")},
+		{"failed.cbz", p("failed.epub"), p("failed.epub")},
+	} {
+		if got := s.output(tc.name, tc.output, ".epub"); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWatchState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), watchStateFile)
+	s, err := loadWatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Files) != 0 {
+		t.Fatalf("new state: got %v", s.Files)
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Files["a.cbz"] = watchEntry{watchSnapshot{12, at}, "success", "a.epub", "", at}
+	if err = s.save(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := loadWatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Files["a.cbz"]; !got.same(watchSnapshot{12, at}) || got.Output != "a.epub" || got.Status != "success" {
+		t.Errorf("got %+v", got)
+	}
+
+	if err = os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadWatchState(path); err == nil || !strings.Contains(err.Error(), "invalid state file") {
+		t.Errorf("invalid state: got %v", err)
+	}
+}
+
+// a directory of images changes when one of them changes
+func TestWatchSnapshotOf(t *testing.T) {
+	dir := t.TempDir()
+	book := filepath.Join(dir, "book")
+	if err := os.Mkdir(book, 0755); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, name := range []string{"01.png", "02.png"} {
+		path := filepath.Join(book, name)
+		if err := os.WriteFile(path, make([]byte, 10*(i+1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(book, at, at); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := watchSnapshotOf(book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snap.same(watchSnapshot{30, at}) {
+		t.Errorf("got %+v", snap)
+	}
+
+	later := at.Add(time.Minute)
+	if err = os.Chtimes(filepath.Join(book, "02.png"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if snap, err = watchSnapshotOf(book); err != nil || !snap.same(watchSnapshot{30, later}) {
+		t.Errorf("got %+v %v", snap, err)
+	}
+}
+
+func TestMoveToDone(t *testing.T) {
+	inbox := t.TempDir()
+	for range 2 {
+		if err := os.WriteFile(filepath.Join(inbox, "a.cbz"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := moveToDone(inbox, "a.cbz"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(inbox, watchDoneDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Name() != "a.cbz" || !strings.HasSuffix(entries[0].Name(), " a.cbz") {
+		t.Errorf("got %v", entries)
+	}
+	if _, err = os.Stat(filepath.Join(inbox, "a.cbz")); !os.IsNotExist(err) {
+		t.Errorf("source not moved: %v", err)
+	}
+}
+
+// a copy keeping the modification time is stable only after 2 identical scans
+func TestWatchPending(t *testing.T) {
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := watchPending{}
+	for i, tc := range []struct {
+		snap watchSnapshot
+		want bool
+	}{
+		{watchSnapshot{10, old}, false},
+		{watchSnapshot{20, old}, false},
+		{watchSnapshot{20, old}, true},
+		{watchSnapshot{20, old}, false},
+		{watchSnapshot{20, old.Add(time.Second)}, false},
+		{watchSnapshot{20, old.Add(time.Second)}, true},
+	} {
+		if got := p.stable("a.cbz", tc.snap); got != tc.want {
+			t.Errorf("scan %d: got %v, want %v", i+1, got, tc.want)
+		}
+	}
+
+	p.stable("a.cbz", watchSnapshot{10, old})
+	p.stable("b.cbz", watchSnapshot{10, old})
+	p.forget(map[string]bool{"b.cbz": true})
+	if _, ok := p["a.cbz"]; ok || len(p) != 1 {
+		t.Errorf("got %v", p)
+	}
+}
+
+func TestWatchStateProcess(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		convert   error
+		doneFile  bool
+		status    string
+		errPrefix string
+		moved     bool
+	}{
+		{name: "converted", status: "success", moved: true},
+		{name: "not converted", convert: errors.New("bad comic"), status: "failed", errPrefix: "bad comic"},
+		{name: "not moved", doneFile: true, status: "not moved", errPrefix: "mkdir"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inbox, outbox := t.TempDir(), t.TempDir()
+			if err := os.WriteFile(filepath.Join(inbox, "a.cbz"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tc.doneFile {
+				if err := os.WriteFile(filepath.Join(inbox, watchDoneDir), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			state, err := loadWatchState(filepath.Join(inbox, watchStateFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := converter.New()
+			cmd.Options.Quiet = true
+			snap := watchSnapshot{Size: 1}
+			state.process(cmd, inbox, outbox, "a.cbz", snap, func(*converter.Converter) error { return tc.convert })
+
+			saved, err := loadWatchState(state.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := saved.Files["a.cbz"]
+			if entry.Status != tc.status || !strings.HasPrefix(entry.Error, tc.errPrefix) || !entry.same(snap) {
+				t.Errorf("got %+v", entry)
+			}
+			if entry.Output != filepath.Join(outbox, "a.epub") {
+				t.Errorf("output: got %s", entry.Output)
+			}
+			_, err = os.Stat(filepath.Join(inbox, watchDoneDir, "a.cbz"))
+			if moved := err == nil; moved != tc.moved {
+				t.Errorf("moved: got %v, want %v", moved, tc.moved)
+			}
+		})
+	}
+}